@@ -0,0 +1,176 @@
+package gox
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// delayItem is an entry of a [DelayQueue].
+type delayItem[Type any] struct {
+	value Type
+	at    time.Time
+	seq   uint64
+}
+
+// DelayHandle refers to an item scheduled in a [DelayQueue] so that it can be
+// cancelled or rescheduled.
+type DelayHandle[Type any] struct {
+	handle *HeapHandle[delayItem[Type]]
+}
+
+// Value returns the scheduled item.
+func (h *DelayHandle[Type]) Value() Type {
+	return h.handle.Value().value
+}
+
+// ReadyAt returns the time the item is due.
+func (h *DelayHandle[Type]) ReadyAt() time.Time {
+	return h.handle.Value().at
+}
+
+// DelayQueue holds items until the time they are scheduled for. Consumers only
+// receive items once they are due, earliest first, with items due at the same
+// time returned in the order they were scheduled.
+//
+// It is backed by a [Heap] and a single timer per waiting consumer, so it scales
+// to large numbers of pending items. It is safe to use from multiple goroutines.
+type DelayQueue[Type any] struct {
+	lock    sync.Mutex
+	heap    Heap[delayItem[Type]]
+	changed chan struct{}
+	seq     uint64
+}
+
+// notifyChanged wakes anyone waiting in [Take]. The lock must be held by the
+// caller.
+func (q *DelayQueue[Type]) notifyChanged() {
+	if q.changed != nil {
+		close(q.changed)
+		q.changed = nil
+	}
+}
+
+// Len returns the number of scheduled items, due or not.
+func (q *DelayQueue[Type]) Len() int {
+	return q.heap.Len()
+}
+
+// Schedule adds the item to be ready at the given time. It returns a handle for
+// cancelling or rescheduling it.
+func (q *DelayQueue[Type]) Schedule(item Type, at time.Time) *DelayHandle[Type] {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	q.seq++
+	handle := q.heap.Push(delayItem[Type]{
+		value: item,
+		at:    at,
+		seq:   q.seq,
+	})
+	q.notifyChanged()
+
+	return &DelayHandle[Type]{handle: handle}
+}
+
+// ScheduleAfter adds the item to be ready once the delay has passed.
+func (q *DelayQueue[Type]) ScheduleAfter(item Type, delay time.Duration) *DelayHandle[Type] {
+	return q.Schedule(item, time.Now().Add(delay))
+}
+
+// Reschedule changes when the item is ready. It returns false if the item has
+// already been taken or cancelled.
+func (q *DelayQueue[Type]) Reschedule(handle *DelayHandle[Type], at time.Time) bool {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	item := handle.handle.Value()
+	item.at = at
+	if !q.heap.Update(handle.handle, item) {
+		return false
+	}
+	q.notifyChanged()
+	return true
+}
+
+// Cancel removes the item from the queue. It returns false if the item has
+// already been taken or cancelled.
+func (q *DelayQueue[Type]) Cancel(handle *DelayHandle[Type]) bool {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	return q.heap.Remove(handle.handle)
+}
+
+// next pops the earliest item if it is due. Otherwise it returns the time the
+// earliest item is due, or false if there are none. The lock must be held by
+// the caller.
+func (q *DelayQueue[Type]) next(now time.Time) (item delayItem[Type], due bool, ok bool) {
+	item, ok = q.heap.Peek()
+	if ok && !item.at.After(now) {
+		q.heap.Pop()
+		due = true
+	}
+	return
+}
+
+// Poll returns the earliest item if it is due, without waiting.
+func (q *DelayQueue[Type]) Poll() (Type, bool) {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	if item, due, _ := q.next(time.Now()); due {
+		return item.value, true
+	}
+	return MakeAny[Type](), false
+}
+
+// Take blocks until an item is due and returns it. If the context is done first
+// it returns the context error.
+func (q *DelayQueue[Type]) Take(ctx context.Context) (Type, error) {
+	for {
+		q.lock.Lock()
+		item, due, ok := q.next(time.Now())
+		if due {
+			q.lock.Unlock()
+			return item.value, nil
+		}
+		if q.changed == nil {
+			q.changed = make(chan struct{})
+		}
+		wait := q.changed
+		q.lock.Unlock()
+
+		var timer *time.Timer
+		var ready <-chan time.Time
+		if ok {
+			timer = time.NewTimer(time.Until(item.at))
+			ready = timer.C
+		}
+
+		select {
+		case <-wait:
+		case <-ready:
+		case <-ctx.Done():
+		}
+		if timer != nil {
+			timer.Stop()
+		}
+
+		if err := ctx.Err(); err != nil {
+			return MakeAny[Type](), err
+		}
+	}
+}
+
+// NewDelayQueue constructs a new empty [DelayQueue].
+func NewDelayQueue[Type any]() DelayQueue[Type] {
+	return DelayQueue[Type]{
+		heap: NewHeap(func(a, b delayItem[Type]) bool {
+			if a.at.Equal(b.at) {
+				return a.seq < b.seq
+			}
+			return a.at.Before(b.at)
+		}),
+	}
+}
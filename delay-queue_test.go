@@ -0,0 +1,65 @@
+package gox
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestDelayQueueTake(t *testing.T) {
+	q := NewDelayQueue[string]()
+
+	now := time.Now()
+	q.Schedule("second", now.Add(20*time.Millisecond))
+	q.Schedule("first", now.Add(10*time.Millisecond))
+	cancelled := q.Schedule("cancelled", now.Add(5*time.Millisecond))
+
+	if !q.Cancel(cancelled) || q.Cancel(cancelled) {
+		t.Error("cancel did not work once")
+	}
+	if _, ok := q.Poll(); ok {
+		t.Error("polled an item before it was due")
+	}
+
+	for _, want := range []string{"first", "second"} {
+		got, err := q.Take(context.Background())
+		if err != nil || got != want {
+			t.Error("incorrect item taken", got, err)
+		}
+	}
+	if time.Since(now) < 20*time.Millisecond {
+		t.Error("item taken before it was due")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Millisecond)
+	defer cancel()
+	q.ScheduleAfter("later", time.Hour)
+	if _, err := q.Take(ctx); err != context.DeadlineExceeded {
+		t.Error("expected deadline error", err)
+	}
+}
+
+func TestDelayQueueReschedule(t *testing.T) {
+	q := NewDelayQueue[int]()
+
+	h := q.ScheduleAfter(1, time.Hour)
+	done := make(chan int)
+	go func() {
+		v, _ := q.Take(context.Background())
+		done <- v
+	}()
+
+	time.Sleep(5 * time.Millisecond)
+	if !q.Reschedule(h, time.Now()) {
+		t.Error("reschedule failed")
+	}
+
+	select {
+	case v := <-done:
+		if v != 1 {
+			t.Error("incorrect item", v)
+		}
+	case <-time.After(time.Second):
+		t.Error("waiting consumer did not notice the reschedule")
+	}
+}
@@ -0,0 +1,183 @@
+package gox
+
+import "sync"
+
+// Deque is a double-ended queue backed by a circular buffer and mutex locks.
+// Items can be pushed and popped from either end in constant time, and accessed
+// by index where 0 is the front.
+//
+// A capacity can be given to cap its size, in which case pushing to a full deque
+// displaces items from the opposite end, the same as [PriorityQueue]. Without a
+// capacity the buffer grows as needed.
+type Deque[Type any] struct {
+	capacity int
+	ring     ringBuffer[Type]
+	lock     sync.RWMutex
+}
+
+// Capacity returns the maximum size of the deque, or 0 if it is unbounded.
+func (d *Deque[Type]) Capacity() int {
+	return d.capacity
+}
+
+// Len returns the number of items in the deque. This is thread-safe.
+func (d *Deque[Type]) Len() int {
+	d.lock.RLock()
+	defer d.lock.RUnlock()
+
+	return d.ring.count
+}
+
+// Slice returns a copy of the contents from front to back. This is thread-safe.
+func (d *Deque[Type]) Slice() []Type {
+	d.lock.RLock()
+	defer d.lock.RUnlock()
+
+	arr := make([]Type, d.ring.count)
+	d.ring.read(arr)
+	return arr
+}
+
+// makeRoom prepares for one more item. If the deque is capped and full it
+// displaces an item from the opposite end to where the new one is going,
+// returning true. The lock must be held exclusively by the caller.
+func (d *Deque[Type]) makeRoom(front bool) bool {
+	if d.capacity <= 0 {
+		d.ring.grow(1)
+		return false
+	}
+
+	if d.ring.free() == 0 && len(d.ring.buf) < d.capacity {
+		d.ring.resize(Min(Max(len(d.ring.buf)*2, 8), d.capacity))
+	}
+	if d.ring.count < d.capacity {
+		return false
+	}
+
+	if front {
+		d.ring.popBack()
+	} else {
+		d.ring.discard(1)
+	}
+	return true
+}
+
+// PushBack adds the items to the back in the order given. It returns the number
+// of items displaced from the front if the deque is capped.
+func (d *Deque[Type]) PushBack(items ...Type) int {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	disp := 0
+	for _, item := range items {
+		if d.makeRoom(false) {
+			disp++
+		}
+		d.ring.push(item)
+	}
+	return disp
+}
+
+// PushFront adds the items to the front in the order given, so the last item
+// ends up at the front. It returns the number of items displaced from the back
+// if the deque is capped.
+func (d *Deque[Type]) PushFront(items ...Type) int {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	disp := 0
+	for _, item := range items {
+		if d.makeRoom(true) {
+			disp++
+		}
+		d.ring.pushFront(item)
+	}
+	return disp
+}
+
+// PopFront removes and returns the item at the front. If the deque is empty it
+// returns false.
+func (d *Deque[Type]) PopFront() (Type, bool) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	if d.ring.count == 0 {
+		return MakeAny[Type](), false
+	}
+
+	item := d.ring.at(0)
+	d.ring.discard(1)
+	return item, true
+}
+
+// PopBack removes and returns the item at the back. If the deque is empty it
+// returns false.
+func (d *Deque[Type]) PopBack() (Type, bool) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	if d.ring.count == 0 {
+		return MakeAny[Type](), false
+	}
+	return d.ring.popBack(), true
+}
+
+// Front returns the item at the front without removing it.
+func (d *Deque[Type]) Front() (Type, bool) {
+	return d.At(0)
+}
+
+// Back returns the item at the back without removing it.
+func (d *Deque[Type]) Back() (Type, bool) {
+	d.lock.RLock()
+	defer d.lock.RUnlock()
+
+	if d.ring.count == 0 {
+		return MakeAny[Type](), false
+	}
+	return d.ring.at(d.ring.count - 1), true
+}
+
+// At returns the item at the index, where 0 is the front. It returns false if
+// the index is out of range.
+func (d *Deque[Type]) At(index int) (Type, bool) {
+	d.lock.RLock()
+	defer d.lock.RUnlock()
+
+	if index < 0 || index >= d.ring.count {
+		return MakeAny[Type](), false
+	}
+	return d.ring.at(index), true
+}
+
+// Set replaces the item at the index, where 0 is the front. It returns false if
+// the index is out of range.
+func (d *Deque[Type]) Set(index int, value Type) bool {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	if index < 0 || index >= d.ring.count {
+		return false
+	}
+	d.ring.set(index, value)
+	return true
+}
+
+// Clear removes every item.
+func (d *Deque[Type]) Clear() {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	d.ring.discard(d.ring.count)
+}
+
+// NewDeque constructs a new [Deque]. A capacity greater than 0 caps the size of
+// the deque, otherwise it grows as needed.
+func NewDeque[Type any](capacity int) Deque[Type] {
+	capacity = Max(capacity, 0)
+
+	return Deque[Type]{
+		capacity: capacity,
+		ring:     newRingBuffer[Type](Min(capacity, 64)),
+	}
+}
@@ -0,0 +1,66 @@
+package gox
+
+import "testing"
+
+func TestDequeEnds(t *testing.T) {
+	d := NewDeque[int](0)
+
+	for i := 0; i < 20; i++ {
+		d.PushBack(i)
+	}
+	d.PushFront(-1, -2)
+
+	if d.Len() != 22 {
+		t.Error("incorrect length", d.Len())
+	}
+	if v, _ := d.Front(); v != -2 {
+		t.Error("incorrect front", v)
+	}
+	if v, _ := d.Back(); v != 19 {
+		t.Error("incorrect back", v)
+	}
+	if v, ok := d.At(2); !ok || v != 0 {
+		t.Error("incorrect indexed item", v)
+	}
+	if _, ok := d.At(22); ok {
+		t.Error("out of range index returned an item")
+	}
+
+	if v, _ := d.PopFront(); v != -2 {
+		t.Error("incorrect pop front", v)
+	}
+	if v, _ := d.PopBack(); v != 19 {
+		t.Error("incorrect pop back", v)
+	}
+	if !d.Set(0, 100) {
+		t.Error("set failed")
+	}
+
+	items := d.Slice()
+	if len(items) != 20 || items[0] != 100 || items[1] != 0 || items[19] != 18 {
+		t.Error("incorrect contents", items)
+	}
+
+	d.Clear()
+	if _, ok := d.PopBack(); ok {
+		t.Error("popped from cleared deque")
+	}
+}
+
+func TestDequeCapacity(t *testing.T) {
+	d := NewDeque[int](3)
+
+	if disp := d.PushBack(1, 2, 3, 4); disp != 1 {
+		t.Error("incorrect displaced from front", disp)
+	}
+	if items := d.Slice(); items[0] != 2 || items[2] != 4 {
+		t.Error("front was not displaced", items)
+	}
+
+	if disp := d.PushFront(0); disp != 1 {
+		t.Error("incorrect displaced from back", disp)
+	}
+	if items := d.Slice(); len(items) != 3 || items[0] != 0 || items[2] != 3 {
+		t.Error("back was not displaced", items)
+	}
+}
@@ -0,0 +1,85 @@
+package gox
+
+import (
+	"context"
+	"strings"
+)
+
+// BusEvent is the payload delivered to [EventBus] subscribers, carrying the
+// topic the payload was published to.
+type BusEvent[Payload any] struct {
+	Topic   LTree
+	Payload Payload
+}
+
+// EventBus routes payloads published to an [LTree] topic to the subscribers whose
+// pattern matches it. Patterns follow [LTree.Match], so "orders.*.created"
+// receives "orders.eu.created", and "orders" receives every topic beneath it.
+//
+// It is built on a single [EventManager] so the same priority, one-shot and
+// re-entrancy rules apply to subscribers.
+type EventBus[Payload any] struct {
+	manager EventManager[BusEvent[Payload]]
+}
+
+// Manager returns the underlying [EventManager] which receives every published
+// event regardless of topic.
+func (b *EventBus[Payload]) Manager() *EventManager[BusEvent[Payload]] {
+	return &b.manager
+}
+
+// Subscribe adds a listener for topics matching the given pattern. If the
+// callback returns true it is removed, the same as [EventManager.Add]. It
+// returns the ID to use with [Unsubscribe].
+func (b *EventBus[Payload]) Subscribe(pattern string, cb func(event BusEvent[Payload]) bool) uint {
+	return b.SubscribeAction(pattern, 0, func(event BusEvent[Payload]) ListenerAction {
+		if cb(event) {
+			return ListenerRemove
+		}
+		return ListenerContinue
+	})
+}
+
+// SubscribeAction adds a listener with a priority for topics matching the given
+// pattern, the same as [EventManager.AddAction].
+func (b *EventBus[Payload]) SubscribeAction(pattern string, priority int, cb func(event BusEvent[Payload]) ListenerAction) uint {
+	query := SplitStringByRune(strings.ToLower(pattern), '.')
+
+	return b.manager.AddAction(priority, func(event BusEvent[Payload]) ListenerAction {
+		if !MatchLTreeSegments(event.Topic.Segments(), query) {
+			return ListenerContinue
+		}
+		return cb(event)
+	})
+}
+
+// Unsubscribe removes the subscriber with the given ID.
+func (b *EventBus[Payload]) Unsubscribe(id uint) {
+	b.manager.Remove(id)
+}
+
+// Publish dispatches the payload to every subscriber matching the topic. It
+// returns the joined errors of the dispatch, see [EventManager.Dispatch].
+func (b *EventBus[Payload]) Publish(topic LTree, payload Payload) error {
+	return b.manager.Dispatch(BusEvent[Payload]{
+		Topic:   topic,
+		Payload: payload,
+	})
+}
+
+// PublishAsync dispatches the payload to every subscriber matching the topic
+// using [EventManager.DispatchAsync].
+func (b *EventBus[Payload]) PublishAsync(ctx context.Context, topic LTree, payload Payload) *DispatchHandle {
+	return b.manager.DispatchAsync(ctx, BusEvent[Payload]{
+		Topic:   topic,
+		Payload: payload,
+	})
+}
+
+// NewEventBus creates a new [EventBus], the options are applied to the
+// underlying [EventManager].
+func NewEventBus[Payload any](opts ...EventOption) EventBus[Payload] {
+	return EventBus[Payload]{
+		manager: NewEventManager[BusEvent[Payload]](opts...),
+	}
+}
@@ -0,0 +1,42 @@
+package gox
+
+import "testing"
+
+func TestEventBus(t *testing.T) {
+	b := NewEventBus[int]()
+
+	wildcard, exact, prefix, other := 0, 0, 0, 0
+	b.Subscribe("orders.*.created", func(event BusEvent[int]) bool {
+		wildcard += event.Payload
+		return false
+	})
+	b.Subscribe("Orders.EU.Created", func(event BusEvent[int]) bool {
+		exact += event.Payload
+		return false
+	})
+	b.Subscribe("orders", func(event BusEvent[int]) bool {
+		prefix += event.Payload
+		return false
+	})
+	b.Subscribe("users.*", func(event BusEvent[int]) bool {
+		other += event.Payload
+		return false
+	})
+
+	b.Publish(NewLTree("orders", "eu", "created"), 1)
+	b.Publish(NewLTree("orders", "us", "created"), 1)
+	b.Publish(NewLTree("orders", "us", "deleted"), 1)
+
+	if wildcard != 2 {
+		t.Error("wildcard subscriber got wrong events", wildcard)
+	}
+	if exact != 1 {
+		t.Error("exact subscriber got wrong events", exact)
+	}
+	if prefix != 3 {
+		t.Error("prefix subscriber got wrong events", prefix)
+	}
+	if other != 0 {
+		t.Error("unrelated subscriber got events", other)
+	}
+}
@@ -0,0 +1,55 @@
+package gox
+
+import "context"
+
+// AddContext adds a new listener like [Add] which is removed automatically once
+// the given context is done. This is useful for listeners scoped to a request
+// that may be abandoned early.
+func (e *EventManager[Payload]) AddContext(ctx context.Context, cb func(payload Payload) bool) uint {
+	id := e.Add(cb)
+
+	stop := context.AfterFunc(ctx, func() {
+		e.Remove(id)
+	})
+	if !e.setOnRemove(id, func() { stop() }) {
+		// Already removed itself, likely during replay
+		stop()
+	}
+
+	return id
+}
+
+// WaitFor blocks until a payload is dispatched that passes the predicate and
+// returns it. If the context is done first then the context error is returned
+// instead. If the manager keeps a history using [WithReplay] then a matching
+// payload in the history is returned immediately.
+func (e *EventManager[Payload]) WaitFor(ctx context.Context, predicate func(payload Payload) bool) (Payload, error) {
+	found := make(chan Payload, 1)
+
+	id := e.AddContext(ctx, func(payload Payload) bool {
+		if !predicate(payload) {
+			return false
+		}
+
+		select {
+		case found <- payload:
+		default:
+		}
+		return true
+	})
+
+	select {
+	case payload := <-found:
+		return payload, nil
+	case <-ctx.Done():
+		e.Remove(id)
+	}
+
+	// A match may have raced the context
+	select {
+	case payload := <-found:
+		return payload, nil
+	default:
+		return MakeAny[Payload](), ctx.Err()
+	}
+}
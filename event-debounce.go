@@ -0,0 +1,117 @@
+package gox
+
+import (
+	"sync"
+	"time"
+)
+
+// debouncer holds back payloads until no more have arrived for the wait period.
+type debouncer[Payload any] struct {
+	lock    sync.Mutex
+	wait    time.Duration
+	timer   *time.Timer
+	gen     uint
+	pending Payload
+	has     bool
+	stopped bool
+	merge   func(pending Payload, payload Payload) Payload
+	cb      func(payload Payload)
+}
+
+func (d *debouncer[Payload]) push(payload Payload) bool {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	if d.stopped {
+		return true
+	}
+
+	if d.has && d.merge != nil {
+		payload = d.merge(d.pending, payload)
+	}
+	d.pending, d.has = payload, true
+
+	// Each push restarts the wait, older timers see a stale generation
+	if d.timer != nil {
+		d.timer.Stop()
+	}
+	d.gen++
+	gen := d.gen
+	d.timer = time.AfterFunc(d.wait, func() {
+		d.fire(gen)
+	})
+
+	return false
+}
+
+func (d *debouncer[Payload]) fire(gen uint) {
+	d.lock.Lock()
+	if d.stopped || !d.has || gen != d.gen {
+		d.lock.Unlock()
+		return
+	}
+	payload := d.pending
+	d.pending, d.has = MakeAny[Payload](), false
+	d.lock.Unlock()
+
+	d.cb(payload)
+}
+
+func (d *debouncer[Payload]) stop() {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	d.stopped = true
+	d.has = false
+	if d.timer != nil {
+		d.timer.Stop()
+	}
+}
+
+// AddDebounced adds a listener which is only called once no payloads have been
+// dispatched for the wait duration, receiving the last payload of the burst.
+//
+// The callback runs on its own goroutine after the wait rather than during the
+// dispatch. Removing the listener discards any payload still waiting.
+func (e *EventManager[Payload]) AddDebounced(wait time.Duration, cb func(payload Payload)) uint {
+	return e.AddDebouncedMerge(wait, nil, cb)
+}
+
+// AddDebouncedMerge is the same as [AddDebounced] except that the payloads of a
+// burst are coalesced using the merge function instead of keeping only the last.
+// The merge function receives the pending payload and the newly dispatched one.
+func (e *EventManager[Payload]) AddDebouncedMerge(wait time.Duration, merge func(pending Payload, payload Payload) Payload, cb func(payload Payload)) uint {
+	d := &debouncer[Payload]{
+		wait:  wait,
+		merge: merge,
+		cb:    cb,
+	}
+
+	id := e.Add(d.push)
+	if !e.setOnRemove(id, d.stop) {
+		d.stop()
+	}
+	return id
+}
+
+// AddThrottled adds a listener which is called at most once per interval. The
+// first payload is delivered straight away, any others dispatched before the
+// interval has passed are dropped.
+func (e *EventManager[Payload]) AddThrottled(interval time.Duration, cb func(payload Payload)) uint {
+	var lock sync.Mutex
+	var last time.Time
+
+	return e.Add(func(payload Payload) bool {
+		lock.Lock()
+		now := time.Now()
+		if !last.IsZero() && now.Sub(last) < interval {
+			lock.Unlock()
+			return false
+		}
+		last = now
+		lock.Unlock()
+
+		cb(payload)
+		return false
+	})
+}
@@ -0,0 +1,134 @@
+package gox
+
+import (
+	"errors"
+	"reflect"
+	"sync"
+)
+
+// hubSubscription locates a [Hub] subscriber within its type's manager.
+type hubSubscription struct {
+	typ reflect.Type
+	id  uint
+}
+
+// Hub routes payloads of any type to the subscribers of that type, avoiding the
+// need for a separate [EventManager] per payload type. Subscribers are added
+// with [Subscribe] and may subscribe to an interface type, in which case they
+// receive every payload implementing it.
+//
+// Each subscribed type is backed by its own [EventManager] so the same priority,
+// one-shot and re-entrancy rules apply.
+type Hub struct {
+	lock     sync.RWMutex
+	types    []reflect.Type
+	managers map[reflect.Type]*EventManager[any]
+	subs     map[uint]hubSubscription
+	lastID   uint
+	opts     []EventOption
+}
+
+// manager returns the manager for the given type, creating it if needed.
+func (h *Hub) manager(typ reflect.Type) *EventManager[any] {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	if h.managers == nil {
+		h.managers = make(map[reflect.Type]*EventManager[any])
+		h.subs = make(map[uint]hubSubscription)
+	}
+
+	mgr, ok := h.managers[typ]
+	if !ok {
+		m := NewEventManager[any](h.opts...)
+		mgr = &m
+		h.managers[typ] = mgr
+		h.types = append(h.types, typ)
+	}
+	return mgr
+}
+
+// Subscribe adds a listener to the hub for payloads of type T. If T is an
+// interface then any payload implementing it is delivered. If the callback
+// returns true it is removed, the same as [EventManager.Add]. It returns the ID
+// to use with [Hub.Unsubscribe].
+func Subscribe[T any](hub *Hub, cb func(payload T) bool) uint {
+	typ := reflect.TypeOf((*T)(nil)).Elem()
+	mgr := hub.manager(typ)
+
+	hub.lock.Lock()
+	hub.lastID++
+	id := hub.lastID
+	hub.lock.Unlock()
+
+	forget := func() {
+		hub.lock.Lock()
+		delete(hub.subs, id)
+		hub.lock.Unlock()
+	}
+
+	managerID := mgr.Add(func(payload any) bool {
+		return cb(payload.(T))
+	})
+
+	hub.lock.Lock()
+	hub.subs[id] = hubSubscription{typ: typ, id: managerID}
+	hub.lock.Unlock()
+
+	if !mgr.setOnRemove(managerID, forget) {
+		// Removed itself during replay
+		forget()
+	}
+	return id
+}
+
+// Unsubscribe removes the subscriber with the given ID.
+func (h *Hub) Unsubscribe(id uint) {
+	h.lock.RLock()
+	sub, ok := h.subs[id]
+	mgr := h.managers[sub.typ]
+	h.lock.RUnlock()
+
+	if ok {
+		mgr.Remove(sub.id)
+	}
+}
+
+// Publish dispatches the payload to the subscribers of its dynamic type, then to
+// those of each interface type it implements in the order they were first
+// subscribed to. A nil payload is ignored. It returns the joined errors of every
+// dispatch.
+func (h *Hub) Publish(payload any) error {
+	typ := reflect.TypeOf(payload)
+	if typ == nil {
+		return nil
+	}
+
+	h.lock.RLock()
+	var targets []*EventManager[any]
+	if mgr, ok := h.managers[typ]; ok {
+		targets = append(targets, mgr)
+	}
+	for _, t := range h.types {
+		if t != typ && t.Kind() == reflect.Interface && typ.Implements(t) {
+			targets = append(targets, h.managers[t])
+		}
+	}
+	h.lock.RUnlock()
+
+	var errs []error
+	for _, mgr := range targets {
+		errs = append(errs, mgr.Dispatch(payload))
+	}
+	return errors.Join(errs...)
+}
+
+// NewHub creates a new [Hub], the options are applied to the [EventManager] of
+// every payload type.
+func NewHub(opts ...EventOption) Hub {
+	return Hub{
+		managers: make(map[reflect.Type]*EventManager[any]),
+		subs:     make(map[uint]hubSubscription),
+		opts:     opts,
+	}
+}
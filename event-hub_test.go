@@ -0,0 +1,51 @@
+package gox
+
+import "testing"
+
+type hubShape interface {
+	Area() int
+}
+
+type hubSquare struct {
+	Side int
+}
+
+func (s hubSquare) Area() int {
+	return s.Side * s.Side
+}
+
+func TestHub(t *testing.T) {
+	hub := NewHub()
+
+	squares, shapes, strs := 0, 0, 0
+	Subscribe(&hub, func(payload hubSquare) bool {
+		squares += payload.Side
+		return false
+	})
+	Subscribe(&hub, func(payload hubShape) bool {
+		shapes += payload.Area()
+		return false
+	})
+	once := Subscribe(&hub, func(payload string) bool {
+		strs++
+		return true
+	})
+
+	hub.Publish(hubSquare{Side: 2})
+	hub.Publish("hello")
+	hub.Publish("world")
+	hub.Publish(nil)
+
+	if squares != 2 {
+		t.Error("concrete subscriber got wrong payloads", squares)
+	}
+	if shapes != 4 {
+		t.Error("interface subscriber got wrong payloads", shapes)
+	}
+	if strs != 1 {
+		t.Error("one-shot subscriber was not removed", strs)
+	}
+	if _, ok := hub.subs[once]; ok {
+		t.Error("one-shot subscription was leaked")
+	}
+}
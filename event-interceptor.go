@@ -0,0 +1,39 @@
+package gox
+
+import "context"
+
+// EventNext continues a dispatch from within an [EventInterceptor], passing the
+// payload on to the next interceptor or finally to the listeners.
+type EventNext[Payload any] func(ctx context.Context, payload Payload) error
+
+// EventInterceptor wraps every dispatch of an [EventManager]. It may inspect or
+// replace the payload before calling next, skip calling next to filter the
+// payload out entirely, or measure and decorate the dispatch as a whole. The
+// error returned by next is that of the listeners and later interceptors.
+type EventInterceptor[Payload any] func(ctx context.Context, payload Payload, next EventNext[Payload]) error
+
+// Use appends interceptors to the manager. They run in the order added, the
+// first being the outermost. Dispatches already in progress are not affected.
+func (e *EventManager[Payload]) Use(interceptors ...EventInterceptor[Payload]) {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+
+	e.interceptors = append(e.interceptors, interceptors...)
+}
+
+// intercept runs the payload through the interceptor chain, ending with final.
+func (e *EventManager[Payload]) intercept(ctx context.Context, payload Payload, final EventNext[Payload]) error {
+	e.lock.RLock()
+	chain := CopySlice(e.interceptors)
+	e.lock.RUnlock()
+
+	next := final
+	for i := len(chain) - 1; i >= 0; i-- {
+		interceptor, inner := chain[i], next
+		next = func(ctx context.Context, payload Payload) error {
+			return interceptor(ctx, payload, inner)
+		}
+	}
+
+	return next(ctx, payload)
+}
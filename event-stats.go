@@ -0,0 +1,145 @@
+package gox
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// EventHook receives notifications from an [EventManager] so that its activity
+// can be bridged into a metrics system. It is set using [WithHook] and must be
+// safe to call concurrently.
+type EventHook interface {
+	// ListenerAdded is called after a listener is registered.
+	ListenerAdded(id uint)
+
+	// ListenerRemoved is called after a listener is removed, whether explicitly
+	// or by the listener asking for it.
+	ListenerRemoved(id uint)
+
+	// ListenerCalled is called after each listener invocation with the time it
+	// took and any error it produced.
+	ListenerCalled(id uint, duration time.Duration, err error)
+
+	// Dispatched is called once a dispatch completes with the number of
+	// listeners that were called and the total time taken.
+	Dispatched(listeners int, duration time.Duration)
+}
+
+// WithHook sets the [EventHook] notified of the manager's activity.
+func WithHook(hook EventHook) EventOption {
+	return func(config *eventConfig) {
+		config.hook = hook
+	}
+}
+
+// EventStats is a snapshot of the counters kept by an [EventManager].
+type EventStats struct {
+	// Listeners is the number of currently registered listeners.
+	Listeners int
+
+	// Dispatches is the number of payloads dispatched, including those received
+	// from transports.
+	Dispatches uint64
+
+	// Calls is the number of individual listener invocations.
+	Calls uint64
+
+	// Removals is the number of listeners that have been removed.
+	Removals uint64
+}
+
+// ListenerStats is a snapshot of the timings of a single listener.
+type ListenerStats struct {
+	ID    uint
+	Calls uint64
+	Total time.Duration
+	Max   time.Duration
+	Last  time.Duration
+}
+
+// Average returns the mean time taken per call.
+func (s ListenerStats) Average() time.Duration {
+	if s.Calls == 0 {
+		return 0
+	}
+	return s.Total / time.Duration(s.Calls)
+}
+
+// listenerStats holds the counters of a single listener.
+type listenerStats struct {
+	calls atomic.Uint64
+	total atomic.Int64
+	max   atomic.Int64
+	last  atomic.Int64
+}
+
+// eventStats holds the counters of an [EventManager].
+type eventStats struct {
+	dispatches atomic.Uint64
+	calls      atomic.Uint64
+	removals   atomic.Uint64
+}
+
+// observe records a listener invocation and notifies the hook.
+func (e *EventManager[Payload]) observe(l *eventListener[Payload], duration time.Duration, err error) {
+	e.stats.calls.Add(1)
+
+	l.stats.calls.Add(1)
+	l.stats.total.Add(int64(duration))
+	l.stats.last.Store(int64(duration))
+	for {
+		prev := l.stats.max.Load()
+		if int64(duration) <= prev || l.stats.max.CompareAndSwap(prev, int64(duration)) {
+			break
+		}
+	}
+
+	if e.config.hook != nil {
+		e.config.hook.ListenerCalled(l.id, duration, err)
+	}
+}
+
+// Stats returns a snapshot of the manager's counters.
+func (e *EventManager[Payload]) Stats() EventStats {
+	return EventStats{
+		Listeners:  e.Len(),
+		Dispatches: e.stats.dispatches.Load(),
+		Calls:      e.stats.calls.Load(),
+		Removals:   e.stats.removals.Load(),
+	}
+}
+
+// ListenerStats returns the timings of the listener with the given ID. It
+// returns false if no such listener is registered.
+func (e *EventManager[Payload]) ListenerStats(id uint) (ListenerStats, bool) {
+	e.lock.RLock()
+	defer e.lock.RUnlock()
+
+	if ind := e.indexOf(id); ind >= 0 {
+		return e.listeners[ind].snapshotStats(), true
+	}
+	return ListenerStats{}, false
+}
+
+// AllListenerStats returns the timings of every registered listener in the
+// order they are called.
+func (e *EventManager[Payload]) AllListenerStats() []ListenerStats {
+	e.lock.RLock()
+	defer e.lock.RUnlock()
+
+	ret := make([]ListenerStats, len(e.listeners))
+	for i, l := range e.listeners {
+		ret[i] = l.snapshotStats()
+	}
+	return ret
+}
+
+func (l *eventListener[Payload]) snapshotStats() ListenerStats {
+	return ListenerStats{
+		ID:    l.id,
+		Calls: l.stats.calls.Load(),
+		Total: time.Duration(l.stats.total.Load()),
+		Max:   time.Duration(l.stats.max.Load()),
+		Last:  time.Duration(l.stats.last.Load()),
+	}
+}
@@ -0,0 +1,150 @@
+package gox
+
+import (
+	"errors"
+	"sync"
+)
+
+// ErrSubscriptionOverflow is returned from the unsubscribe function of a
+// subscription using [BackpressureClose] once its buffer overflowed.
+var ErrSubscriptionOverflow = errors.New("subscription buffer overflowed")
+
+// BackpressurePolicy declares what a channel subscription from [Subscribe] does
+// when a payload is dispatched while its buffer is full.
+type BackpressurePolicy uint8
+
+const (
+	// BackpressureBlock blocks the dispatch until the subscriber receives the
+	// payload, or unsubscribes.
+	BackpressureBlock BackpressurePolicy = iota
+
+	// BackpressureDropNewest discards the incoming payload, keeping the buffered
+	// ones.
+	BackpressureDropNewest
+
+	// BackpressureDropOldest discards the oldest buffered payload to make room
+	// for the incoming one.
+	BackpressureDropOldest
+
+	// BackpressureClose closes the channel and removes the subscription. The
+	// unsubscribe function will then return [ErrSubscriptionOverflow].
+	BackpressureClose
+)
+
+// subscription adapts a channel to an [EventManager] listener.
+type subscription[Payload any] struct {
+	lock   sync.Mutex
+	ch     chan Payload
+	done   chan struct{}
+	once   sync.Once
+	closed bool
+	err    error
+	policy BackpressurePolicy
+}
+
+func (s *subscription[Payload]) closeLocked() {
+	if !s.closed {
+		s.closed = true
+		close(s.ch)
+	}
+}
+
+func (s *subscription[Payload]) deliver(payload Payload) ListenerAction {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.closed {
+		return ListenerRemove
+	}
+
+	switch s.policy {
+	case BackpressureBlock:
+		select {
+		case s.ch <- payload:
+		case <-s.done:
+			return ListenerRemove
+		}
+	case BackpressureDropNewest:
+		select {
+		case s.ch <- payload:
+		default:
+		}
+	case BackpressureDropOldest:
+		s.overwrite(payload)
+	case BackpressureClose:
+		select {
+		case s.ch <- payload:
+		default:
+			s.err = ErrSubscriptionOverflow
+			s.closeLocked()
+			return ListenerRemove
+		}
+	}
+
+	return ListenerContinue
+}
+
+// overwrite buffers a payload without blocking, dropping the oldest if needed. The
+// lock must be held by the caller.
+func (s *subscription[Payload]) overwrite(payload Payload) {
+	for {
+		select {
+		case s.ch <- payload:
+			return
+		default:
+		}
+
+		select {
+		case <-s.ch:
+		default:
+		}
+	}
+}
+
+func (s *subscription[Payload]) unsubscribe() error {
+	// Release any dispatch blocked on sending before taking the lock
+	s.once.Do(func() {
+		close(s.done)
+	})
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.closeLocked()
+	return s.err
+}
+
+// Subscribe registers a listener that delivers payloads to the returned channel
+// instead of a callback. The channel is buffered with the given size (at least
+// 1) and the policy decides what happens when the buffer is full.
+//
+// The returned function unsubscribes and closes the channel, it is safe to call
+// more than once. It returns [ErrSubscriptionOverflow] if the subscription was
+// closed by the [BackpressureClose] policy.
+//
+// If the manager keeps a history with [WithReplay] the channel starts with as
+// much of it as fits in the buffer, keeping the most recent payloads.
+func (e *EventManager[Payload]) Subscribe(bufferSize int, policy BackpressurePolicy) (<-chan Payload, func() error) {
+	sub := &subscription[Payload]{
+		ch:     make(chan Payload, Max(bufferSize, 1)),
+		done:   make(chan struct{}),
+		policy: policy,
+	}
+
+	// Hold the subscription while replaying so that new payloads queue up behind
+	// the history.
+	sub.lock.Lock()
+	listener, history := e.insert(0, func(payload Payload) (ListenerAction, error) {
+		return sub.deliver(payload), nil
+	})
+	for _, payload := range history {
+		sub.overwrite(payload)
+	}
+	sub.lock.Unlock()
+	listener.gate.Unlock()
+
+	return sub.ch, func() error {
+		e.Remove(listener.id)
+		return sub.unsubscribe()
+	}
+}
@@ -0,0 +1,251 @@
+package gox
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"sync/atomic"
+)
+
+// EventTransport carries payloads between an [EventManager] and other processes.
+// Implementations must allow Send to be called concurrently with Receive.
+type EventTransport[Payload any] interface {
+	// Send delivers the payload to the remote side.
+	Send(payload Payload) error
+
+	// Receive blocks until a payload arrives from the remote side. It should
+	// return an error once the transport is closed. If a single payload can not
+	// be decoded, but the transport is still usable, it should return a
+	// [TransportDecodeError] so that receiving carries on.
+	Receive() (Payload, error)
+
+	// Close shuts down the transport, unblocking any pending Receive.
+	Close() error
+}
+
+// TransportDecodeError is returned from [EventTransport.Receive] when a payload
+// was received in full but could not be decoded. Unlike other errors it does not
+// end the receiving of an attached transport.
+type TransportDecodeError struct {
+	Err error
+}
+
+func (e *TransportDecodeError) Error() string {
+	return "event transport: decoding payload: " + e.Err.Error()
+}
+
+func (e *TransportDecodeError) Unwrap() error {
+	return e.Err
+}
+
+// WithTransportErrors sets a function to be called with the errors received from
+// transports added with [Attach]. It is called for every [TransportDecodeError],
+// for the error which stops a transport from receiving (unless that was caused
+// by detaching it), and with the result of dispatching a received payload if it
+// failed, such as the [ListenerError] values of its listeners.
+func WithTransportErrors(fn func(err error)) EventOption {
+	return func(config *eventConfig) {
+		config.onTransport = fn
+	}
+}
+
+// maxTransportFrame limits the size of a single encoded payload read by
+// [ConnTransport] to guard against corrupt length prefixes.
+const maxTransportFrame = 64 << 20
+
+// ConnTransport is an [EventTransport] over any [net.Conn], such as a Unix
+// socket or TCP connection. Payloads are encoded with [JSONMarshaler] and sent
+// as frames prefixed by their length.
+type ConnTransport[Payload any] struct {
+	conn      net.Conn
+	reader    *bufio.Reader
+	writeLock sync.Mutex
+	readLock  sync.Mutex
+}
+
+// Send encodes the payload and writes it to the connection.
+func (t *ConnTransport[Payload]) Send(payload Payload) error {
+	data, err := JSONMarshaler(payload)
+	if err != nil {
+		return err
+	}
+
+	frame := make([]byte, 4+len(data))
+	binary.BigEndian.PutUint32(frame, uint32(len(data)))
+	copy(frame[4:], data)
+
+	t.writeLock.Lock()
+	defer t.writeLock.Unlock()
+
+	_, err = t.conn.Write(frame)
+	return err
+}
+
+// Receive reads the next frame from the connection and decodes it.
+func (t *ConnTransport[Payload]) Receive() (payload Payload, err error) {
+	t.readLock.Lock()
+	defer t.readLock.Unlock()
+
+	var header [4]byte
+	if _, err = io.ReadFull(t.reader, header[:]); err != nil {
+		return
+	}
+
+	size := binary.BigEndian.Uint32(header[:])
+	if size > maxTransportFrame {
+		err = fmt.Errorf("event transport frame of %d bytes exceeds limit", size)
+		return
+	}
+
+	data := make([]byte, size)
+	if _, err = io.ReadFull(t.reader, data); err != nil {
+		return
+	}
+
+	if err = JSONUnmarshaler(data, &payload); err != nil {
+		err = &TransportDecodeError{Err: err}
+	}
+	return
+}
+
+// Close closes the underlying connection.
+func (t *ConnTransport[Payload]) Close() error {
+	return t.conn.Close()
+}
+
+// NewConnTransport creates a new [ConnTransport] using the given connection.
+func NewConnTransport[Payload any](conn net.Conn) *ConnTransport[Payload] {
+	return &ConnTransport[Payload]{
+		conn:   conn,
+		reader: bufio.NewReader(conn),
+	}
+}
+
+// transportLink is an attached transport, held by pointer so it can be found
+// again on detach.
+type transportLink[Payload any] struct {
+	transport EventTransport[Payload]
+	done      chan struct{}
+	detached  atomic.Bool
+	err       error
+}
+
+// remoteEventKey marks the context of a dispatch that came from a transport.
+type remoteEventKey struct{}
+
+// IsRemoteEvent returns true if the context belongs to a dispatch of a payload
+// received through an [EventTransport]. Interceptors can use this to tell local
+// and remote events apart.
+func IsRemoteEvent(ctx context.Context) bool {
+	return ctx.Value(remoteEventKey{}) != nil
+}
+
+// Attach connects the manager to a transport. Every payload dispatched locally
+// is sent through it, and every payload received from it is dispatched locally
+// as if [DispatchContext] was called.
+//
+// Payloads received from a transport are not forwarded on to any transport, so
+// connecting managers in a loop will not echo events forever.
+//
+// A payload which fails to decode is skipped, any other error from
+// [EventTransport.Receive] stops the transport and detaches it from the
+// manager. These errors, and those from dispatching received payloads, are
+// reported to the function set with [WithTransportErrors].
+//
+// The returned function detaches and closes the transport, returning the
+// result of [EventTransport.Close] joined with the error which stopped it from
+// receiving, if any.
+func (e *EventManager[Payload]) Attach(transport EventTransport[Payload]) func() error {
+	link := &transportLink[Payload]{
+		transport: transport,
+		done:      make(chan struct{}),
+	}
+
+	e.lock.Lock()
+	e.transports = append(e.transports, link)
+	e.lock.Unlock()
+
+	go func() {
+		defer close(link.done)
+
+		ctx := context.WithValue(context.Background(), remoteEventKey{}, transport)
+		for {
+			payload, err := transport.Receive()
+
+			var decodeErr *TransportDecodeError
+			if errors.As(err, &decodeErr) {
+				e.transportError(err)
+				continue
+			} else if err != nil {
+				if !link.detached.Load() {
+					link.err = fmt.Errorf("event transport: %w", err)
+					e.detach(link)
+					e.transportError(link.err)
+				}
+				return
+			}
+
+			if err := e.DispatchContext(ctx, payload); err != nil {
+				e.transportError(err)
+			}
+		}
+	}()
+
+	var once sync.Once
+	var err error
+	return func() error {
+		once.Do(func() {
+			link.detached.Store(true)
+			e.detach(link)
+
+			err = transport.Close()
+			<-link.done
+			err = errors.Join(link.err, err)
+		})
+		return err
+	}
+}
+
+// detach removes the link from the transports used to forward payloads.
+func (e *EventManager[Payload]) detach(link *transportLink[Payload]) {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+
+	if ind := SliceFindIndex(e.transports, func(l *transportLink[Payload]) bool {
+		return l == link
+	}); ind >= 0 {
+		e.transports = append(e.transports[:ind], e.transports[ind+1:]...)
+	}
+}
+
+// transportError reports an error from an attached transport, if a function was
+// set with [WithTransportErrors].
+func (e *EventManager[Payload]) transportError(err error) {
+	if e.config.onTransport != nil {
+		e.config.onTransport(err)
+	}
+}
+
+// forward sends a locally dispatched payload through every attached transport.
+func (e *EventManager[Payload]) forward(ctx context.Context, payload Payload) error {
+	if IsRemoteEvent(ctx) {
+		return nil
+	}
+
+	e.lock.RLock()
+	links := CopySlice(e.transports)
+	e.lock.RUnlock()
+
+	var errs []error
+	for _, link := range links {
+		if err := link.transport.Send(payload); err != nil {
+			errs = append(errs, fmt.Errorf("event transport: %w", err))
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -1,55 +1,587 @@
 package gox
 
-import "sync"
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// EventOption configures an [EventManager] when passed to [NewEventManager].
+type EventOption func(config *eventConfig)
+
+// eventConfig holds the settings applied by [EventOption] values.
+type eventConfig struct {
+	asyncWorkers  int
+	recoverPanics bool
+	history       int
+	hook          EventHook
+	onTransport   func(err error)
+}
+
+// WithAsyncWorkers sets the maximum number of listeners that may be running at
+// once across all [DispatchAsync] calls on the manager. Values less than 1 use
+// the default of [runtime.GOMAXPROCS].
+func WithAsyncWorkers(count int) EventOption {
+	return func(config *eventConfig) {
+		config.asyncWorkers = count
+	}
+}
+
+// WithPanicRecovery recovers any panic raised by a listener and reports it as a
+// [ListenerError] from the dispatch instead of crashing the dispatching
+// goroutine. The remaining listeners are still called.
+func WithPanicRecovery() EventOption {
+	return func(config *eventConfig) {
+		config.recoverPanics = true
+	}
+}
+
+// WithReplay keeps a history of the last given number of dispatched payloads.
+// Every newly registered listener is called with that history, oldest first,
+// before it receives any new payloads.
+//
+// Replayed payloads are only sent to the new listener. Errors returned during
+// the replay are discarded, and [ListenerStop] has no effect.
+func WithReplay(count int) EventOption {
+	return func(config *eventConfig) {
+		config.history = count
+	}
+}
+
+// WithSticky keeps the most recently dispatched payload and replays it to every
+// newly registered listener. It is the same as WithReplay(1).
+func WithSticky() EventOption {
+	return WithReplay(1)
+}
+
+// ListenerError wraps an error returned by, or a panic recovered from, a single
+// listener during a dispatch.
+type ListenerError struct {
+	// ID is the ID of the listener that failed.
+	ID uint
+
+	// Err is the error the listener returned. For panics it describes the
+	// recovered value.
+	Err error
+
+	// Recovered holds the value passed to panic, or nil if the listener returned
+	// an error normally.
+	Recovered any
+}
+
+func (e *ListenerError) Error() string {
+	if e.Recovered != nil {
+		return fmt.Sprintf("event listener %d panicked: %v", e.ID, e.Recovered)
+	}
+	return fmt.Sprintf("event listener %d: %v", e.ID, e.Err)
+}
+
+func (e *ListenerError) Unwrap() error {
+	return e.Err
+}
+
+// ListenerAction is returned by listeners registered with [AddAction] to control
+// what happens after they are called. The values are flags and can be combined.
+type ListenerAction uint8
+
+const (
+	// ListenerContinue keeps the listener registered and continues dispatching.
+	ListenerContinue ListenerAction = 0
+
+	// ListenerRemove removes the listener from the manager, the same as returning
+	// true from a listener registered with [Add].
+	ListenerRemove ListenerAction = 1 << (iota - 1)
+
+	// ListenerStop prevents the payload from being sent to any of the remaining
+	// listeners of lower priority in this dispatch.
+	ListenerStop
+)
+
+// Has returns true if the given flag is set in this action.
+func (a ListenerAction) Has(flag ListenerAction) bool {
+	return a&flag != 0
+}
+
+// eventListener is a single registered callback within an [EventManager].
+type eventListener[Payload any] struct {
+	id       uint
+	priority int
+	call     func(payload Payload) (ListenerAction, error)
+	removed  atomic.Bool
+	gate     sync.Mutex
+	onRemove func()
+	stats    listenerStats
+}
+
+// awaitReplay waits until the listener has been replayed the history.
+func (l *eventListener[Payload]) awaitReplay() {
+	l.gate.Lock()
+	l.gate.Unlock()
+}
 
 // EventManager is a simple thread-safe dispatcher for managing callbacks in the
 // case of events.
 //
 // Any listener that returns true when called will remove itself. This is good for
 // one-shot event listeners.
+//
+// Listeners are called in order of priority, highest first. Listeners sharing
+// the same priority are called in the order they were registered.
+//
+// Listeners are invoked without any lock held, so they are free to call [Add],
+// [Remove] or even [Dispatch] on the same manager. Each dispatch works from a
+// snapshot of the listeners taken when it starts: listeners added during a
+// dispatch will not receive the in-flight payload, and listeners removed during
+// a dispatch are skipped if they have not been called yet.
+//
+// Because no lock is held, dispatches running at the same time on different
+// goroutines may call the same listener concurrently. Once a call to a listener
+// has returned true no later dispatch will reach it, but dispatches which had
+// already reached it still call it, so a one-shot listener can run more than
+// once when dispatches overlap. Listeners which keep state, or must only ever run
+// once, need to guard themselves, for instance with a [sync.Once].
 type EventManager[Payload any] struct {
 	lock      sync.RWMutex
-	listeners map[uint]func(payload Payload) bool
+	listeners []*eventListener[Payload]
 	lastID    uint
+	config    eventConfig
+	workers   chan struct{}
+	history   FixedArray[Payload]
+	stats     eventStats
+
+	interceptors []EventInterceptor[Payload]
+	transports   []*transportLink[Payload]
 }
 
 // Add adds a new listener to the event manager with the given callback. It
 // returns the ID for the listener so that it can be removed later with [Remove]
 func (e *EventManager[Payload]) Add(cb func(payload Payload) bool) uint {
-	e.lock.RLock()
-	defer e.lock.RUnlock()
+	return e.AddWithPriority(0, cb)
+}
+
+// AddWithPriority adds a new listener like [Add] but with the given priority.
+// Listeners with a higher priority are called before those with a lower one.
+func (e *EventManager[Payload]) AddWithPriority(priority int, cb func(payload Payload) bool) uint {
+	return e.AddAction(priority, func(payload Payload) ListenerAction {
+		if cb(payload) {
+			return ListenerRemove
+		}
+		return ListenerContinue
+	})
+}
+
+// AddAction adds a new listener with the given priority whose callback returns
+// a [ListenerAction]. This allows the listener to stop the propagation of the
+// payload to lower priority listeners, as well as removing itself.
+//
+// If the manager keeps a history with [WithReplay] then the listener is called
+// with it before this returns. Dispatches reaching the new listener during the
+// replay wait for it to finish, so the listener never sees a newer payload
+// before the history. It must therefore not dispatch on the same manager while
+// being replayed to, as that dispatch would wait on itself.
+func (e *EventManager[Payload]) AddAction(priority int, cb func(payload Payload) ListenerAction) uint {
+	return e.AddFallible(priority, func(payload Payload) (ListenerAction, error) {
+		return cb(payload), nil
+	})
+}
+
+// AddFallible adds a new listener with the given priority whose callback may
+// fail. Any errors returned are wrapped in a [ListenerError] and joined together
+// as the result of [Dispatch]. The action is honoured even when an error is
+// returned.
+func (e *EventManager[Payload]) AddFallible(priority int, cb func(payload Payload) (ListenerAction, error)) uint {
+	listener, history := e.insert(priority, cb)
+
+	for _, payload := range history {
+		if listener.removed.Load() {
+			break
+		}
+		e.invoke(listener, payload)
+	}
+	listener.gate.Unlock()
+
+	return listener.id
+}
+
+// insert registers the callback as a new listener in priority order. It returns
+// the listener along with a copy of the history to be replayed to it.
+//
+// The listener is returned with its gate locked, holding back dispatches until
+// the caller has replayed the history and unlocked it.
+func (e *EventManager[Payload]) insert(priority int, cb func(payload Payload) (ListenerAction, error)) (*eventListener[Payload], []Payload) {
+	e.lock.Lock()
 
 	e.lastID++
-	e.listeners[e.lastID] = cb
+	listener := &eventListener[Payload]{
+		id:       e.lastID,
+		priority: priority,
+		call:     cb,
+	}
+	listener.gate.Lock()
+
+	// Insert after every listener of equal or higher priority so that equal
+	// priorities keep their registration order.
+	ind := SliceFindIndex(e.listeners, func(l *eventListener[Payload]) bool {
+		return l.priority < priority
+	})
+	if ind < 0 {
+		e.listeners = append(e.listeners, listener)
+	} else {
+		e.listeners = append(e.listeners, nil)
+		copy(e.listeners[ind+1:], e.listeners[ind:])
+		e.listeners[ind] = listener
+	}
 
-	return e.lastID
+	history := e.history.Elements()
+	e.lock.Unlock()
+
+	if e.config.hook != nil {
+		e.config.hook.ListenerAdded(listener.id)
+	}
+
+	return listener, history
 }
 
 // Remove removes the listener with the given ID
 func (e *EventManager[Payload]) Remove(id uint) {
+	var onRemove func()
+
+	e.lock.Lock()
+	ind := e.indexOf(id)
+	if ind >= 0 {
+		onRemove = e.listeners[ind].onRemove
+		e.listeners[ind].removed.Store(true)
+		e.listeners = append(e.listeners[:ind], e.listeners[ind+1:]...)
+	}
+	e.lock.Unlock()
+
+	if ind < 0 {
+		return
+	}
+
+	e.stats.removals.Add(1)
+	if e.config.hook != nil {
+		e.config.hook.ListenerRemoved(id)
+	}
+	if onRemove != nil {
+		onRemove()
+	}
+}
+
+// setOnRemove attaches a function to be called once the listener is removed.
+// It returns false if the listener is no longer registered.
+func (e *EventManager[Payload]) setOnRemove(id uint, fn func()) bool {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+
+	if ind := e.indexOf(id); ind >= 0 {
+		e.listeners[ind].onRemove = fn
+		return true
+	}
+	return false
+}
+
+// Len returns the number of listeners currently registered.
+func (e *EventManager[Payload]) Len() int {
 	e.lock.RLock()
 	defer e.lock.RUnlock()
 
-	delete(e.listeners, id)
+	return len(e.listeners)
 }
 
-// Dispatch sends the payload to all the listeners in the list. If any of the
-// listeners return true, then they will be removed from the manager.
-func (e *EventManager[Payload]) Dispatch(payload Payload) {
+// indexOf returns the index of the listener with the given ID, or -1. The lock
+// must be held by the caller.
+func (e *EventManager[Payload]) indexOf(id uint) int {
+	return SliceFindIndex(e.listeners, func(l *eventListener[Payload]) bool {
+		return l.id == id
+	})
+}
+
+// snapshot copies the current listeners so they can be called without holding
+// the lock.
+func (e *EventManager[Payload]) snapshot() []*eventListener[Payload] {
+	e.lock.RLock()
+	defer e.lock.RUnlock()
+
+	return CopySlice(e.listeners)
+}
+
+// begin records the payload into the history, if one is kept, and returns a
+// snapshot of the listeners to send it to.
+func (e *EventManager[Payload]) begin(payload Payload) []*eventListener[Payload] {
+	e.stats.dispatches.Add(1)
+
+	if e.config.history <= 0 {
+		return e.snapshot()
+	}
+
 	e.lock.Lock()
 	defer e.lock.Unlock()
 
-	for k, cb := range e.listeners {
-		if cb(payload) {
-			// Returning true means to remove itself
-			delete(e.listeners, k)
+	e.history.Push(payload)
+	return CopySlice(e.listeners)
+}
+
+// History returns a copy of the payloads kept for replay, oldest first. It is
+// empty unless the manager was created using [WithReplay] or [WithSticky].
+func (e *EventManager[Payload]) History() []Payload {
+	e.lock.RLock()
+	defer e.lock.RUnlock()
+
+	return e.history.Elements()
+}
+
+// ClearHistory forgets the payloads kept for replay.
+func (e *EventManager[Payload]) ClearHistory() {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+
+	e.history.Reset()
+}
+
+// invoke calls the listener and applies the removal action. Errors are wrapped
+// as a [ListenerError], as are panics when recovery is enabled.
+func (e *EventManager[Payload]) invoke(l *eventListener[Payload], payload Payload) (action ListenerAction, err error) {
+	start := time.Now()
+	defer func() {
+		e.observe(l, time.Since(start), err)
+	}()
+
+	if e.config.recoverPanics {
+		defer func() {
+			if r := recover(); r != nil {
+				action = ListenerContinue
+				err = &ListenerError{
+					ID:        l.id,
+					Err:       fmt.Errorf("panic: %v", r),
+					Recovered: r,
+				}
+			}
+		}()
+	}
+
+	action, err = l.call(payload)
+	if action.Has(ListenerRemove) {
+		e.Remove(l.id)
+	}
+	if err != nil {
+		err = &ListenerError{ID: l.id, Err: err}
+	}
+	return
+}
+
+// Dispatch sends the payload to all the listeners in the list in order of
+// priority. If any of the listeners return true, then they will be removed from
+// the manager. A listener returning [ListenerStop] ends the dispatch.
+//
+// The listeners are those registered at the time Dispatch is called. A listener
+// which is removed before its turn is not called.
+//
+// Errors from listeners added with [AddFallible] are joined and returned once
+// the dispatch is complete.
+func (e *EventManager[Payload]) Dispatch(payload Payload) error {
+	return e.DispatchContext(context.Background(), payload)
+}
+
+// DispatchContext is the same as [Dispatch] but passes the context on to any
+// interceptors added with [Use]. Once the context is done no more listeners are
+// called and its error is included in the result.
+func (e *EventManager[Payload]) DispatchContext(ctx context.Context, payload Payload) error {
+	return e.intercept(ctx, payload, e.dispatch)
+}
+
+func (e *EventManager[Payload]) dispatch(ctx context.Context, payload Payload) error {
+	start := time.Now()
+	called := 0
+	if e.config.hook != nil {
+		defer func() {
+			e.config.hook.Dispatched(called, time.Since(start))
+		}()
+	}
+
+	errs := []error{e.forward(ctx, payload)}
+	for _, l := range e.begin(payload) {
+		if l.awaitReplay(); l.removed.Load() {
+			continue
+		} else if err := ctx.Err(); err != nil {
+			errs = append(errs, err)
+			break
+		}
+
+		called++
+		action, err := e.invoke(l, payload)
+		if err != nil {
+			errs = append(errs, err)
+		}
+		if action.Has(ListenerStop) {
+			break
 		}
 	}
+	return errors.Join(errs...)
 }
 
-func NewEventManager[Payload any]() EventManager[Payload] {
+// DispatchHandle tracks an asynchronous dispatch started with [DispatchAsync].
+type DispatchHandle struct {
+	done chan struct{}
+	err  error
+}
+
+// Done returns a channel that is closed once every listener of the dispatch has
+// finished, or the dispatch was abandoned due to its context.
+func (h *DispatchHandle) Done() <-chan struct{} {
+	return h.done
+}
+
+// Wait blocks until the dispatch is complete. It returns the joined errors of the
+// listeners, along with the context error if it was cancelled before all the
+// listeners could be called.
+func (h *DispatchHandle) Wait() error {
+	<-h.done
+	return h.err
+}
+
+func (h *DispatchHandle) finish(err error) {
+	h.err = err
+	close(h.done)
+}
+
+// workerPool returns the semaphore limiting concurrent async listeners, creating
+// it on first use.
+func (e *EventManager[Payload]) workerPool() chan struct{} {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+
+	if e.workers == nil {
+		count := e.config.asyncWorkers
+		if count < 1 {
+			count = runtime.GOMAXPROCS(0)
+		}
+		e.workers = make(chan struct{}, count)
+	}
+	return e.workers
+}
+
+// DispatchAsync sends the payload to the listeners on separate goroutines and
+// returns immediately with a handle to await the results.
+//
+// Listeners sharing a priority are run concurrently, limited by the number of
+// workers set with [WithAsyncWorkers]. Each priority level finishes before the
+// next lower one is started, so [ListenerStop] still prevents lower priority
+// listeners from being called. Once the context is done no more listeners are
+// started, though those already running are allowed to finish.
+//
+// Interceptors added with [Use] wrap the whole of the dispatch, so next returns
+// once every listener has finished.
+//
+// Listeners which wait on another async dispatch of the same manager can exhaust
+// the workers and deadlock.
+func (e *EventManager[Payload]) DispatchAsync(ctx context.Context, payload Payload) *DispatchHandle {
+	handle := &DispatchHandle{
+		done: make(chan struct{}),
+	}
+
+	go func() {
+		handle.finish(e.intercept(ctx, payload, e.dispatchAsync))
+	}()
+
+	return handle
+}
+
+func (e *EventManager[Payload]) dispatchAsync(ctx context.Context, payload Payload) error {
+	start := time.Now()
+	var called atomic.Int32
+	if e.config.hook != nil {
+		defer func() {
+			e.config.hook.Dispatched(int(called.Load()), time.Since(start))
+		}()
+	}
+
+	workers := e.workerPool()
+	listeners := e.begin(payload)
+
+	var errsLock sync.Mutex
+	errs := []error{e.forward(ctx, payload)}
+
+	for first := 0; first < len(listeners); {
+		// Find the end of this priority level
+		end := first + 1
+		for end < len(listeners) && listeners[end].priority == listeners[first].priority {
+			end++
+		}
+
+		var wg sync.WaitGroup
+		var stop atomic.Bool
+		var err error
+
+		for _, l := range listeners[first:end] {
+			if l.awaitReplay(); l.removed.Load() {
+				continue
+			}
+
+			// Select picks at random when both are ready, so check the context
+			// before and after taking a worker.
+			if err = ctx.Err(); err != nil {
+				break
+			}
+			select {
+			case workers <- struct{}{}:
+				if err = ctx.Err(); err != nil {
+					<-workers
+				}
+			case <-ctx.Done():
+				err = ctx.Err()
+			}
+			if err != nil {
+				break
+			}
+
+			wg.Add(1)
+			called.Add(1)
+			go func(l *eventListener[Payload]) {
+				defer func() {
+					<-workers
+					wg.Done()
+				}()
+
+				action, err := e.invoke(l, payload)
+				if err != nil {
+					errsLock.Lock()
+					errs = append(errs, err)
+					errsLock.Unlock()
+				}
+				if action.Has(ListenerStop) {
+					stop.Store(true)
+				}
+			}(l)
+		}
+
+		wg.Wait()
+		if err != nil {
+			return errors.Join(append(errs, err)...)
+		} else if stop.Load() {
+			break
+		}
+
+		first = end
+	}
+
+	return errors.Join(errs...)
+}
+
+// NewEventManager creates a new [EventManager] with the given options applied.
+func NewEventManager[Payload any](opts ...EventOption) EventManager[Payload] {
+	var config eventConfig
+	for _, opt := range opts {
+		opt(&config)
+	}
+
 	return EventManager[Payload]{
-		listeners: make(map[uint]func(payload Payload) bool),
-		lastID:    1,
+		lastID:  1,
+		config:  config,
+		history: NewFixedArray[Payload](config.history),
 	}
 }
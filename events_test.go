@@ -0,0 +1,680 @@
+package gox
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestEventManagerDispatch(t *testing.T) {
+	e := NewEventManager[int]()
+
+	count := 0
+	e.Add(func(payload int) bool {
+		count += payload
+		return false
+	})
+	e.Add(func(payload int) bool {
+		count += payload
+		return true
+	})
+
+	e.Dispatch(1)
+	if count != 2 {
+		t.Error("incorrect count after first dispatch", count)
+	}
+
+	e.Dispatch(1)
+	if count != 3 {
+		t.Error("one-shot listener was not removed", count)
+	}
+}
+
+func TestEventManagerReentrant(t *testing.T) {
+	e := NewEventManager[int]()
+
+	added := 0
+	e.Add(func(payload int) bool {
+		// Registering from inside a listener must not deadlock, and the new
+		// listener must not see the in-flight payload.
+		e.Add(func(payload int) bool {
+			added++
+			return false
+		})
+
+		if payload == 0 {
+			e.Dispatch(payload + 1)
+		}
+		return true
+	})
+
+	e.Dispatch(0)
+	if added != 1 {
+		t.Error("listener added during dispatch saw wrong payloads", added)
+	}
+}
+
+func TestEventManagerConcurrentOneShot(t *testing.T) {
+	e := NewEventManager[int]()
+
+	var once sync.Once
+	var calls, runs atomic.Int32
+	e.Add(func(payload int) bool {
+		calls.Add(1)
+		once.Do(func() {
+			runs.Add(1)
+		})
+		time.Sleep(time.Millisecond)
+		return true
+	})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			e.Dispatch(0)
+		}()
+	}
+	wg.Wait()
+
+	if runs.Load() != 1 || calls.Load() < 1 {
+		t.Error("one-shot listener was not called once", calls.Load(), runs.Load())
+	}
+	if e.Len() != 0 {
+		t.Error("one-shot listener was not removed", e.Len())
+	}
+
+	before := calls.Load()
+	e.Dispatch(0)
+	if calls.Load() != before {
+		t.Error("removed one-shot listener was called again")
+	}
+}
+
+func TestEventManagerPriority(t *testing.T) {
+	e := NewEventManager[int]()
+
+	order := []int{}
+	record := func(n int) func(int) bool {
+		return func(int) bool {
+			order = append(order, n)
+			return false
+		}
+	}
+
+	e.Add(record(3))
+	e.AddWithPriority(10, record(1))
+	e.Add(record(4))
+	e.AddWithPriority(5, record(2))
+	e.AddWithPriority(-1, record(5))
+
+	e.Dispatch(0)
+	for i, v := range order {
+		if v != i+1 {
+			t.Error("listeners called out of order", order)
+			break
+		}
+	}
+}
+
+func TestEventManagerStopPropagation(t *testing.T) {
+	e := NewEventManager[int]()
+
+	called := 0
+	e.AddAction(1, func(payload int) ListenerAction {
+		called++
+		return ListenerStop | ListenerRemove
+	})
+	e.Add(func(payload int) bool {
+		called += 10
+		return false
+	})
+
+	e.Dispatch(0)
+	if called != 1 {
+		t.Error("propagation was not stopped", called)
+	}
+
+	e.Dispatch(0)
+	if called != 11 {
+		t.Error("stopping listener was not removed", called)
+	}
+	if e.Len() != 1 {
+		t.Error("incorrect listener count", e.Len())
+	}
+}
+
+func TestEventManagerDispatchAsync(t *testing.T) {
+	e := NewEventManager[int](WithAsyncWorkers(2))
+
+	var running, peak, total atomic.Int32
+	for i := 0; i < 6; i++ {
+		e.Add(func(payload int) bool {
+			n := running.Add(1)
+			for {
+				p := peak.Load()
+				if n <= p || peak.CompareAndSwap(p, n) {
+					break
+				}
+			}
+			time.Sleep(5 * time.Millisecond)
+			running.Add(-1)
+			total.Add(int32(payload))
+			return false
+		})
+	}
+
+	if err := e.DispatchAsync(context.Background(), 1).Wait(); err != nil {
+		t.Error("unexpected error", err)
+	}
+	if total.Load() != 6 {
+		t.Error("not all listeners were called", total.Load())
+	}
+	if peak.Load() > 2 {
+		t.Error("worker limit exceeded", peak.Load())
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	for i := 0; i < 50; i++ {
+		if err := e.DispatchAsync(ctx, 1).Wait(); !errors.Is(err, context.Canceled) {
+			t.Error("expected cancellation error", err)
+		}
+	}
+	if total.Load() != 6 {
+		t.Error("listeners were called with a cancelled context", total.Load())
+	}
+}
+
+func TestEventManagerSubscribe(t *testing.T) {
+	e := NewEventManager[int]()
+
+	oldest, unsubOldest := e.Subscribe(2, BackpressureDropOldest)
+	newest, unsubNewest := e.Subscribe(2, BackpressureDropNewest)
+	closing, unsubClosing := e.Subscribe(2, BackpressureClose)
+
+	e.Dispatch(1)
+	e.Dispatch(2)
+	e.Dispatch(3)
+
+	if a, b := <-oldest, <-oldest; a != 2 || b != 3 {
+		t.Error("drop oldest kept wrong payloads", a, b)
+	}
+	if a, b := <-newest, <-newest; a != 1 || b != 2 {
+		t.Error("drop newest kept wrong payloads", a, b)
+	}
+
+	<-closing
+	<-closing
+	if _, ok := <-closing; ok {
+		t.Error("overflowed channel was not closed")
+	}
+	if err := unsubClosing(); err != ErrSubscriptionOverflow {
+		t.Error("expected overflow error", err)
+	}
+
+	unsubOldest()
+	unsubNewest()
+	if _, ok := <-oldest; ok {
+		t.Error("unsubscribe did not close the channel")
+	}
+	if e.Len() != 0 {
+		t.Error("subscriptions were not removed", e.Len())
+	}
+}
+
+func TestEventManagerSubscribeBlock(t *testing.T) {
+	e := NewEventManager[int]()
+
+	ch, unsub := e.Subscribe(1, BackpressureBlock)
+	e.Dispatch(1)
+
+	done := make(chan struct{})
+	go func() {
+		e.Dispatch(2)
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		t.Error("dispatch did not block on a full subscription")
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	unsub()
+	<-done
+	if v := <-ch; v != 1 {
+		t.Error("buffered payload was lost", v)
+	}
+}
+
+func TestEventManagerErrors(t *testing.T) {
+	e := NewEventManager[int](WithPanicRecovery())
+
+	failure := errors.New("failure")
+	failing := e.AddFallible(0, func(payload int) (ListenerAction, error) {
+		return ListenerContinue, failure
+	})
+	panicking := e.Add(func(payload int) bool {
+		panic("boom")
+	})
+	called := false
+	e.Add(func(payload int) bool {
+		called = true
+		return false
+	})
+
+	err := e.Dispatch(0)
+	if !called {
+		t.Error("listeners after a panic were not called")
+	}
+	if !errors.Is(err, failure) {
+		t.Error("returned error was not joined", err)
+	}
+
+	var listenerErr *ListenerError
+	if !errors.As(err, &listenerErr) || listenerErr.ID != failing {
+		t.Error("error was not wrapped with the listener ID", err)
+	}
+
+	found := false
+	for _, err := range err.(interface{ Unwrap() []error }).Unwrap() {
+		if errors.As(err, &listenerErr) && listenerErr.Recovered != nil {
+			found = listenerErr.ID == panicking && listenerErr.Recovered == "boom"
+		}
+	}
+	if !found {
+		t.Error("panic was not reported", err)
+	}
+
+	if err := e.DispatchAsync(context.Background(), 0).Wait(); !errors.Is(err, failure) {
+		t.Error("async dispatch did not report errors", err)
+	}
+}
+
+func TestEventManagerReplay(t *testing.T) {
+	e := NewEventManager[int](WithReplay(2))
+
+	e.Dispatch(1)
+	e.Dispatch(2)
+	e.Dispatch(3)
+
+	got := []int{}
+	e.Add(func(payload int) bool {
+		got = append(got, payload)
+		return false
+	})
+	if len(got) != 2 || got[0] != 2 || got[1] != 3 {
+		t.Error("incorrect replay", got)
+	}
+
+	once := 0
+	e.Add(func(payload int) bool {
+		once++
+		return true
+	})
+	if once != 1 || e.Len() != 1 {
+		t.Error("one-shot listener was not removed during replay", once)
+	}
+
+	ch, unsub := e.Subscribe(1, BackpressureBlock)
+	if v := <-ch; v != 3 {
+		t.Error("subscription did not start with latest payload", v)
+	}
+	unsub()
+
+	sticky := NewEventManager[string](WithSticky())
+	sticky.Dispatch("connected")
+	if h := sticky.History(); len(h) != 1 || h[0] != "connected" {
+		t.Error("sticky payload was not kept", h)
+	}
+	sticky.ClearHistory()
+	if len(sticky.History()) != 0 {
+		t.Error("history was not cleared")
+	}
+}
+
+func TestEventManagerReplayOrder(t *testing.T) {
+	e := NewEventManager[int](WithSticky())
+	e.Dispatch(1)
+
+	replaying := make(chan struct{})
+	added := make(chan struct{})
+	var seen []int
+	go func() {
+		defer close(added)
+		e.Add(func(payload int) bool {
+			seen = append(seen, payload)
+			if payload == 1 {
+				close(replaying)
+				time.Sleep(10 * time.Millisecond)
+			}
+			return false
+		})
+	}()
+
+	<-replaying
+	e.Dispatch(2)
+	<-added
+
+	if len(seen) != 2 || seen[0] != 1 || seen[1] != 2 {
+		t.Error("dispatch overtook the replay", seen)
+	}
+}
+
+func TestEventManagerInterceptors(t *testing.T) {
+	e := NewEventManager[int](WithSticky())
+
+	order := []string{}
+	e.Use(func(ctx context.Context, payload int, next EventNext[int]) error {
+		order = append(order, "outer")
+		if payload < 0 {
+			return nil
+		}
+		return next(ctx, payload)
+	}, func(ctx context.Context, payload int, next EventNext[int]) error {
+		order = append(order, "inner")
+		return next(ctx, payload*10)
+	})
+
+	got := []int{}
+	e.Add(func(payload int) bool {
+		got = append(got, payload)
+		return false
+	})
+
+	e.Dispatch(1)
+	e.Dispatch(-1)
+	e.DispatchAsync(context.Background(), 2).Wait()
+
+	if len(got) != 2 || got[0] != 10 || got[1] != 20 {
+		t.Error("payloads were not filtered and transformed", got)
+	}
+	if len(order) != 5 || order[0] != "outer" || order[1] != "inner" || order[2] != "outer" {
+		t.Error("interceptors ran in the wrong order", order)
+	}
+	if h := e.History(); len(h) != 1 || h[0] != 20 {
+		t.Error("history did not record the transformed payload", h)
+	}
+}
+
+func TestEventManagerAddContext(t *testing.T) {
+	e := NewEventManager[int]()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	e.AddContext(ctx, func(payload int) bool {
+		return false
+	})
+	if e.Len() != 1 {
+		t.Error("listener was not added")
+	}
+
+	cancel()
+	deadline := time.Now().Add(time.Second)
+	for e.Len() != 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if e.Len() != 0 {
+		t.Error("listener was not removed when the context was cancelled")
+	}
+}
+
+func TestEventManagerWaitFor(t *testing.T) {
+	e := NewEventManager[int]()
+
+	stop := make(chan struct{})
+	go func() {
+		for i := 0; ; i++ {
+			select {
+			case <-stop:
+				return
+			case <-time.After(time.Millisecond):
+				e.Dispatch(i % 5)
+			}
+		}
+	}()
+
+	got, err := e.WaitFor(context.Background(), func(payload int) bool {
+		return payload == 3
+	})
+	close(stop)
+	if err != nil || got != 3 {
+		t.Error("did not wait for the matching payload", got, err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Millisecond)
+	defer cancel()
+	if _, err := e.WaitFor(ctx, func(int) bool { return false }); !errors.Is(err, context.DeadlineExceeded) {
+		t.Error("expected deadline error", err)
+	}
+	if e.Len() != 0 {
+		t.Error("waiting listeners were leaked", e.Len())
+	}
+}
+
+func TestEventManagerDebounced(t *testing.T) {
+	e := NewEventManager[int]()
+
+	last := make(chan int, 4)
+	e.AddDebounced(20*time.Millisecond, func(payload int) {
+		last <- payload
+	})
+	merged := make(chan int, 4)
+	e.AddDebouncedMerge(20*time.Millisecond, func(pending, payload int) int {
+		return pending + payload
+	}, func(payload int) {
+		merged <- payload
+	})
+
+	for i := 1; i <= 4; i++ {
+		e.Dispatch(i)
+	}
+
+	if v := <-last; v != 4 {
+		t.Error("debounced listener got wrong payload", v)
+	}
+	if v := <-merged; v != 10 {
+		t.Error("payloads were not merged", v)
+	}
+
+	time.Sleep(40 * time.Millisecond)
+	if len(last) != 0 || len(merged) != 0 {
+		t.Error("debounced listener fired more than once")
+	}
+}
+
+func TestEventManagerThrottled(t *testing.T) {
+	e := NewEventManager[int]()
+
+	got := []int{}
+	e.AddThrottled(time.Hour, func(payload int) {
+		got = append(got, payload)
+	})
+
+	e.Dispatch(1)
+	e.Dispatch(2)
+	e.Dispatch(3)
+	if len(got) != 1 || got[0] != 1 {
+		t.Error("throttled listener was not limited", got)
+	}
+}
+
+func TestEventManagerTransport(t *testing.T) {
+	type message struct {
+		Text string
+	}
+
+	local := NewEventManager[message]()
+	remote := NewEventManager[message]()
+
+	a, b := net.Pipe()
+	detachLocal := local.Attach(NewConnTransport[message](a))
+	detachRemote := remote.Attach(NewConnTransport[message](b))
+
+	got := make(chan message, 1)
+	remote.Add(func(payload message) bool {
+		got <- payload
+		return false
+	})
+	echoed := make(chan message, 1)
+	local.Add(func(payload message) bool {
+		echoed <- payload
+		return false
+	})
+
+	if err := local.Dispatch(message{Text: "hello"}); err != nil {
+		t.Error("unexpected dispatch error", err)
+	}
+
+	select {
+	case m := <-got:
+		if m.Text != "hello" {
+			t.Error("incorrect payload received", m)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("payload was not received")
+	}
+
+	<-echoed
+	select {
+	case <-echoed:
+		t.Error("remote payload was echoed back")
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	detachLocal()
+	detachRemote()
+	if err := local.Dispatch(message{}); err != nil {
+		t.Error("detached transport was still used", err)
+	}
+}
+
+func TestEventManagerTransportBadFrame(t *testing.T) {
+	type message struct {
+		Text string
+	}
+
+	errs := make(chan error, 2)
+	e := NewEventManager[message](WithTransportErrors(func(err error) {
+		errs <- err
+	}))
+
+	a, b := net.Pipe()
+	detach := e.Attach(NewConnTransport[message](b))
+
+	got := make(chan message, 1)
+	e.Add(func(payload message) bool {
+		got <- payload
+		return false
+	})
+
+	raw := []byte("not json")
+	frame := append([]byte{0, 0, 0, byte(len(raw))}, raw...)
+	go func() {
+		a.Write(frame)
+		NewConnTransport[message](a).Send(message{Text: "after"})
+	}()
+
+	var decodeErr *TransportDecodeError
+	if err := <-errs; !errors.As(err, &decodeErr) {
+		t.Error("bad frame was not reported as a decode error", err)
+	}
+	select {
+	case m := <-got:
+		if m.Text != "after" {
+			t.Error("incorrect payload received", m)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("receiving stopped after a bad frame")
+	}
+
+	a.Close()
+	select {
+	case err := <-errs:
+		if errors.As(err, &decodeErr) {
+			t.Error("closed connection reported as a decode error", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("closed connection was not reported")
+	}
+	if err := e.Dispatch(message{}); err != nil {
+		t.Error("stopped transport was still used", err)
+	}
+	if err := detach(); err == nil {
+		t.Error("detach did not return the receive error")
+	}
+}
+
+func TestEventManagerTransportListenerError(t *testing.T) {
+	errs := make(chan error, 1)
+	e := NewEventManager[int](WithTransportErrors(func(err error) {
+		errs <- err
+	}))
+
+	failure := errors.New("failure")
+	e.AddFallible(0, func(payload int) (ListenerAction, error) {
+		return ListenerContinue, failure
+	})
+
+	a, b := net.Pipe()
+	detach := e.Attach(NewConnTransport[int](b))
+	defer detach()
+	go NewConnTransport[int](a).Send(1)
+
+	var listenerErr *ListenerError
+	select {
+	case err := <-errs:
+		if !errors.As(err, &listenerErr) || !errors.Is(err, failure) {
+			t.Error("listener error was not reported", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("remote dispatch error was dropped")
+	}
+	a.Close()
+}
+
+type countingHook struct {
+	added, removed, called, dispatched atomic.Int32
+}
+
+func (h *countingHook) ListenerAdded(id uint)                                     { h.added.Add(1) }
+func (h *countingHook) ListenerRemoved(id uint)                                   { h.removed.Add(1) }
+func (h *countingHook) ListenerCalled(id uint, duration time.Duration, err error) { h.called.Add(1) }
+func (h *countingHook) Dispatched(listeners int, duration time.Duration)          { h.dispatched.Add(1) }
+
+func TestEventManagerStats(t *testing.T) {
+	hook := &countingHook{}
+	e := NewEventManager[int](WithHook(hook))
+
+	slow := e.Add(func(payload int) bool {
+		time.Sleep(time.Millisecond)
+		return false
+	})
+	e.Add(func(payload int) bool {
+		return true
+	})
+
+	e.Dispatch(0)
+	e.Dispatch(0)
+
+	stats := e.Stats()
+	if stats.Listeners != 1 || stats.Dispatches != 2 || stats.Calls != 3 || stats.Removals != 1 {
+		t.Error("incorrect stats", stats)
+	}
+
+	ls, ok := e.ListenerStats(slow)
+	if !ok || ls.Calls != 2 {
+		t.Error("incorrect listener stats", ls)
+	} else if ls.Max < time.Millisecond || ls.Average() < time.Millisecond {
+		t.Error("listener latency not recorded", ls)
+	}
+
+	if hook.added.Load() != 2 || hook.removed.Load() != 1 || hook.called.Load() != 3 || hook.dispatched.Load() != 2 {
+		t.Error("hook was not notified correctly")
+	}
+}
@@ -1,5 +1,10 @@
 package gox
 
+import (
+	"bytes"
+	"encoding/gob"
+)
+
 // FixedArray is a data structure for holding a fixed amount of elements. Any new
 // elements pushed will evict the older elements.
 //
@@ -36,17 +41,17 @@ func (a FixedArray[Type]) Size() int {
 // Count returns the current size of the array as it is filled and will never
 // exceed [Size].
 func (a FixedArray[Type]) Count() int {
-	return a.ind
+	return a.endInd()
 }
 
 // IsFull returns true if the array is considered full
 func (a FixedArray[Type]) IsFull() bool {
-	return a.ind >= a.size
+	return a.ind >= a.size-1
 }
 
 // Reset clears the array
 func (a *FixedArray[Type]) Reset() {
-	a.arr = a.arr[:0]
+	clear(a.arr)
 	a.ind = -1
 }
 
@@ -75,7 +80,7 @@ func (a FixedArray[Type]) Elements() (ret []Type) {
 
 // shift moves all the elements down in the array
 func (a *FixedArray[Type]) shift(count int) {
-	a.arr = a.arr[count:]
+	copy(a.arr, a.arr[count:])
 	a.ind -= count
 	if a.ind < -1 {
 		a.ind = -1
@@ -83,14 +88,15 @@ func (a *FixedArray[Type]) shift(count int) {
 }
 
 func (a *FixedArray[Type]) pushElement(elem Type) {
-	if a.ind >= a.size {
+	if a.size <= 0 {
+		return
+	}
+
+	if a.ind >= a.size-1 {
 		a.shift(1)
-		a.arr[a.size-1] = elem
-		a.ind = a.size
-	} else {
-		a.ind++
-		a.arr[a.safeInd()] = elem
 	}
+	a.ind++
+	a.arr[a.ind] = elem
 }
 
 // Push adds new elements on-top of this array. They are added in the order they
@@ -114,15 +120,94 @@ func (a FixedArray[Type]) Copy() (ret FixedArray[Type]) {
 	return
 }
 
+// FixedArraySnapshot holds the size and elements of a [FixedArray], oldest
+// first. Unlike the array itself, which encodes to JSON as a plain list of
+// elements, encoding a snapshot keeps the size so that the array can be
+// restored exactly. [UnmarshalJSON] accepts either form.
+type FixedArraySnapshot[Type any] struct {
+	Size     int    `json:"size"`
+	Elements []Type `json:"elements"`
+}
+
+// Snapshot returns the size and a copy of the elements of the array.
+func (a FixedArray[Type]) Snapshot() FixedArraySnapshot[Type] {
+	return FixedArraySnapshot[Type]{
+		Size:     a.size,
+		Elements: a.Elements(),
+	}
+}
+
+func (a *FixedArray[Type]) restore(snap FixedArraySnapshot[Type]) {
+	*a = NewFixedArray(snap.Size, snap.Elements...)
+}
+
+// MarshalJSON encodes the elements of the array as a JSON array, oldest first.
+// The size is not included, use [Snapshot] to keep it.
 func (a FixedArray[Type]) MarshalJSON() ([]byte, error) {
 	return JSONMarshaler(a.Elements())
 }
 
-// NewFixedArray creates an instantiates a new FixedArray of the given size.
+// UnmarshalJSON decodes either a JSON array of elements, as encoded by
+// [MarshalJSON], or an encoded [FixedArraySnapshot]. For a plain array the
+// current size is kept, or the number of elements is used if there is none.
+func (a *FixedArray[Type]) UnmarshalJSON(src []byte) error {
+	var snap FixedArraySnapshot[Type]
+	if trimmed := bytes.TrimSpace(src); len(trimmed) > 0 && trimmed[0] == '[' {
+		if err := JSONUnmarshaler(src, &snap.Elements); err != nil {
+			return err
+		}
+		snap.Size = a.size
+		if snap.Size <= 0 {
+			snap.Size = len(snap.Elements)
+		}
+	} else if err := JSONUnmarshaler(src, &snap); err != nil {
+		return err
+	}
+
+	a.restore(snap)
+	return nil
+}
+
+// MarshalBinary encodes the array, including its size, using [encoding/gob].
+func (a FixedArray[Type]) MarshalBinary() ([]byte, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(a.Snapshot()); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// UnmarshalBinary decodes an array encoded by [MarshalBinary].
+func (a *FixedArray[Type]) UnmarshalBinary(src []byte) error {
+	var snap FixedArraySnapshot[Type]
+	if err := gob.NewDecoder(bytes.NewReader(src)).Decode(&snap); err != nil {
+		return err
+	}
+
+	a.restore(snap)
+	return nil
+}
+
+// GobEncode implements [gob.GobEncoder] using [MarshalBinary].
+func (a FixedArray[Type]) GobEncode() ([]byte, error) {
+	return a.MarshalBinary()
+}
+
+// GobDecode implements [gob.GobDecoder] using [UnmarshalBinary].
+func (a *FixedArray[Type]) GobDecode(src []byte) error {
+	return a.UnmarshalBinary(src)
+}
+
+// NewFixedArray creates an instantiates a new FixedArray of the given size. Any
+// elements given are pushed into it in order.
 func NewFixedArray[Type any](size int, elems ...Type) FixedArray[Type] {
-	return FixedArray[Type]{
+	size = Max(size, 0)
+
+	arr := FixedArray[Type]{
 		size: size,
 		arr:  make([]Type, size),
 		ind:  -1,
 	}
+	arr.Push(elems...)
+	return arr
 }
@@ -0,0 +1,118 @@
+package gox
+
+import (
+	"bytes"
+	"encoding/gob"
+	"testing"
+)
+
+func TestFixedArrayPush(t *testing.T) {
+	a := NewFixedArray[int](3)
+
+	if a.Oldest() != nil || a.Youngest() != nil {
+		t.Error("empty array returned elements")
+	}
+
+	a.Push(1, 2)
+	if a.Count() != 2 {
+		t.Error("incorrect count", a.Count())
+	} else if a.IsFull() {
+		t.Error("says full too early")
+	}
+
+	a.Push(3, 4, 5)
+	if a.Count() != 3 {
+		t.Error("count exceeded size", a.Count())
+	} else if !a.IsFull() {
+		t.Error("says not full")
+	}
+
+	elems := a.Elements()
+	if len(elems) != 3 || elems[0] != 3 || elems[1] != 4 || elems[2] != 5 {
+		t.Error("incorrect elements", elems)
+	}
+	if *a.Oldest() != 3 || *a.Youngest() != 5 {
+		t.Error("incorrect oldest or youngest")
+	}
+
+	a.Reset()
+	if a.Count() != 0 {
+		t.Error("reset did not clear")
+	}
+	a.Push(6)
+	if elems := a.Elements(); len(elems) != 1 || elems[0] != 6 {
+		t.Error("incorrect elements after reset", elems)
+	}
+}
+
+func TestFixedArrayInitialElements(t *testing.T) {
+	a := NewFixedArray(2, 1, 2, 3)
+
+	if elems := a.Elements(); len(elems) != 2 || elems[0] != 2 || elems[1] != 3 {
+		t.Error("initial elements not pushed", elems)
+	}
+}
+
+func TestFixedArrayJSON(t *testing.T) {
+	a := NewFixedArray(4, 1, 2, 3)
+
+	data, err := a.MarshalJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "[1,2,3]" {
+		t.Error("not encoded as a plain array", string(data))
+	}
+
+	b := NewFixedArray[int](2)
+	if err := b.UnmarshalJSON(data); err != nil {
+		t.Fatal(err)
+	}
+	if elems := b.Elements(); b.Size() != 2 || len(elems) != 2 || elems[0] != 2 {
+		t.Error("plain array was not decoded into the existing size", elems)
+	}
+
+	var c FixedArray[int]
+	if err := c.UnmarshalJSON(data); err != nil {
+		t.Fatal(err)
+	}
+	if c.Size() != 3 || c.Count() != 3 {
+		t.Error("plain array was not decoded into its own size", c.Size(), c.Count())
+	}
+
+	data, err = JSONMarshaler(a.Snapshot())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var d FixedArray[int]
+	if err := d.UnmarshalJSON(data); err != nil {
+		t.Fatal(err)
+	}
+	if d.Size() != 4 || d.Count() != 3 {
+		t.Error("size was not preserved", d.Size(), d.Count())
+	}
+	if elems := d.Elements(); elems[0] != 1 || elems[2] != 3 {
+		t.Error("incorrect elements", elems)
+	}
+}
+
+func TestFixedArrayGob(t *testing.T) {
+	type holder struct {
+		Values FixedArray[string]
+	}
+
+	var buf bytes.Buffer
+	in := holder{Values: NewFixedArray(3, "a", "b")}
+	if err := gob.NewEncoder(&buf).Encode(in); err != nil {
+		t.Fatal(err)
+	}
+
+	var out holder
+	if err := gob.NewDecoder(&buf).Decode(&out); err != nil {
+		t.Fatal(err)
+	}
+	if out.Values.Size() != 3 || out.Values.Count() != 2 || *out.Values.Youngest() != "b" {
+		t.Error("array was not restored", out.Values.Elements())
+	}
+}
@@ -0,0 +1,167 @@
+package gox
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+)
+
+var (
+	// ErrInvalidTransition is returned by [FSM.Fire] when there is no transition
+	// for the event from the current state.
+	ErrInvalidTransition = errors.New("no transition for event")
+
+	// ErrTransitionRejected is returned by [FSM.Fire] when every transition for
+	// the event was rejected by its guard.
+	ErrTransitionRejected = errors.New("transition rejected by guard")
+)
+
+// TransitionEvent describes a single transition of an [FSM].
+type TransitionEvent[State comparable, Event comparable] struct {
+	From  State
+	To    State
+	Event Event
+}
+
+// Transition is an entry in the transition table of an [FSM]. When the machine
+// is in the From state and receives the Event it moves to the To state.
+//
+// Several transitions may share the same From and Event, in which case the first
+// one whose Guard passes is taken.
+type Transition[State comparable, Event comparable] struct {
+	From  State
+	Event Event
+	To    State
+
+	// Guard optionally decides if the transition may be taken.
+	Guard func(transition TransitionEvent[State, Event]) bool
+
+	// Action is optionally called during the transition, after the exit action
+	// of the From state and before the entry action of the To state.
+	Action func(transition TransitionEvent[State, Event])
+}
+
+// FSM is a thread-safe finite state machine driven by a declarative table of
+// [Transition] entries. Every transition taken is published through an
+// [EventManager] once it is complete.
+//
+// Guards, actions, and entry and exit actions run while the machine is locked
+// and must not call back into it. Listeners of [Transitions] are called after
+// the lock is released, so they are free to fire further events.
+type FSM[State comparable, Event comparable] struct {
+	lock    sync.Mutex
+	current State
+	table   map[State]map[Event][]Transition[State, Event]
+	entry   map[State]func(transition TransitionEvent[State, Event])
+	exit    map[State]func(transition TransitionEvent[State, Event])
+	events  EventManager[TransitionEvent[State, Event]]
+}
+
+// Current returns the state the machine is in.
+func (m *FSM[State, Event]) Current() State {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	return m.current
+}
+
+// find returns the transition to take for the event, the lock must be held by
+// the caller.
+func (m *FSM[State, Event]) find(event Event) (Transition[State, Event], error) {
+	candidates, ok := m.table[m.current][event]
+	if !ok {
+		return Transition[State, Event]{}, fmt.Errorf("%w %v in state %v", ErrInvalidTransition, event, m.current)
+	}
+
+	for _, t := range candidates {
+		if t.Guard == nil || t.Guard(TransitionEvent[State, Event]{From: t.From, To: t.To, Event: event}) {
+			return t, nil
+		}
+	}
+	return Transition[State, Event]{}, fmt.Errorf("%w for %v in state %v", ErrTransitionRejected, event, m.current)
+}
+
+// Can returns true if firing the event would currently cause a transition.
+func (m *FSM[State, Event]) Can(event Event) bool {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	_, err := m.find(event)
+	return err == nil
+}
+
+// Fire sends the event to the machine, taking the matching transition. It
+// returns [ErrInvalidTransition] or [ErrTransitionRejected] if no transition can
+// be taken, otherwise the errors of publishing the transition.
+func (m *FSM[State, Event]) Fire(event Event) error {
+	m.lock.Lock()
+	t, err := m.find(event)
+	if err != nil {
+		m.lock.Unlock()
+		return err
+	}
+
+	transition := TransitionEvent[State, Event]{
+		From:  t.From,
+		To:    t.To,
+		Event: event,
+	}
+
+	if fn, ok := m.exit[transition.From]; ok {
+		fn(transition)
+	}
+	if t.Action != nil {
+		t.Action(transition)
+	}
+	m.current = transition.To
+	if fn, ok := m.entry[transition.To]; ok {
+		fn(transition)
+	}
+	m.lock.Unlock()
+
+	return m.events.Dispatch(transition)
+}
+
+// OnEnter sets the action called whenever the machine enters the given state,
+// replacing any previous one.
+func (m *FSM[State, Event]) OnEnter(state State, fn func(transition TransitionEvent[State, Event])) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	m.entry[state] = fn
+}
+
+// OnExit sets the action called whenever the machine leaves the given state,
+// replacing any previous one.
+func (m *FSM[State, Event]) OnExit(state State, fn func(transition TransitionEvent[State, Event])) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	m.exit[state] = fn
+}
+
+// Transitions returns the [EventManager] publishing every transition taken.
+func (m *FSM[State, Event]) Transitions() *EventManager[TransitionEvent[State, Event]] {
+	return &m.events
+}
+
+// NewFSM creates a new [FSM] starting in the initial state and using the given
+// transition table. The options are applied to the [EventManager] publishing the
+// transitions.
+func NewFSM[State comparable, Event comparable](initial State, transitions []Transition[State, Event], opts ...EventOption) FSM[State, Event] {
+	table := make(map[State]map[Event][]Transition[State, Event])
+	for _, t := range transitions {
+		if _, ok := table[t.From]; !ok {
+			table[t.From] = make(map[Event][]Transition[State, Event])
+		}
+		table[t.From][t.Event] = append(table[t.From][t.Event], t)
+	}
+
+	return FSM[State, Event]{
+		current: initial,
+		table:   table,
+		entry:   make(map[State]func(transition TransitionEvent[State, Event])),
+		exit:    make(map[State]func(transition TransitionEvent[State, Event])),
+		events:  NewEventManager[TransitionEvent[State, Event]](opts...),
+	}
+}
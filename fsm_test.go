@@ -0,0 +1,53 @@
+package gox
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestFSM(t *testing.T) {
+	paid := false
+	m := NewFSM("pending", []Transition[string, string]{
+		{From: "pending", Event: "ship", To: "shipped", Guard: func(TransitionEvent[string, string]) bool {
+			return paid
+		}},
+		{From: "pending", Event: "cancel", To: "cancelled"},
+		{From: "shipped", Event: "deliver", To: "delivered"},
+	})
+
+	order := []string{}
+	m.OnExit("pending", func(TransitionEvent[string, string]) {
+		order = append(order, "exit")
+	})
+	m.OnEnter("shipped", func(TransitionEvent[string, string]) {
+		order = append(order, "enter")
+	})
+	m.Transitions().Add(func(payload TransitionEvent[string, string]) bool {
+		order = append(order, payload.From+">"+payload.To)
+		return false
+	})
+
+	if err := m.Fire("deliver"); !errors.Is(err, ErrInvalidTransition) {
+		t.Error("expected invalid transition", err)
+	}
+	if err := m.Fire("ship"); !errors.Is(err, ErrTransitionRejected) {
+		t.Error("expected guard rejection", err)
+	}
+	if m.Current() != "pending" {
+		t.Error("state changed on failure", m.Current())
+	}
+
+	paid = true
+	if !m.Can("ship") {
+		t.Error("guard did not pass")
+	}
+	if err := m.Fire("ship"); err != nil {
+		t.Error("unexpected error", err)
+	}
+	if m.Current() != "shipped" {
+		t.Error("incorrect state", m.Current())
+	}
+	if len(order) != 3 || order[0] != "exit" || order[1] != "enter" || order[2] != "pending>shipped" {
+		t.Error("actions ran in the wrong order", order)
+	}
+}
@@ -0,0 +1,182 @@
+package gox
+
+import (
+	"container/heap"
+	"sync"
+)
+
+// HeapHandle refers to an item pushed into a [Heap] so that it can later be
+// updated or removed.
+type HeapHandle[Type any] struct {
+	heap  *Heap[Type]
+	value Type
+	index int
+}
+
+// Value returns the current value of the item. This is thread-safe.
+func (h *HeapHandle[Type]) Value() Type {
+	h.heap.lock.RLock()
+	defer h.heap.lock.RUnlock()
+
+	return h.value
+}
+
+// heapItems adapts the heap contents to [heap.Interface].
+type heapItems[Type any] struct {
+	items []*HeapHandle[Type]
+	less  func(a, b Type) bool
+}
+
+func (h *heapItems[Type]) Len() int {
+	return len(h.items)
+}
+
+func (h *heapItems[Type]) Less(i, j int) bool {
+	return h.less(h.items[i].value, h.items[j].value)
+}
+
+func (h *heapItems[Type]) Swap(i, j int) {
+	h.items[i], h.items[j] = h.items[j], h.items[i]
+	h.items[i].index = i
+	h.items[j].index = j
+}
+
+func (h *heapItems[Type]) Push(x any) {
+	item := x.(*HeapHandle[Type])
+	item.index = len(h.items)
+	h.items = append(h.items, item)
+}
+
+func (h *heapItems[Type]) Pop() any {
+	last := len(h.items) - 1
+	item := h.items[last]
+	h.items[last] = nil
+	h.items = h.items[:last]
+	item.index = -1
+	return item
+}
+
+// Heap is a priority queue backed by a binary heap and mutex locks. The order is
+// decided by the less function given at creation, the item for which it is
+// true against all others is popped first. Pushing and popping are O(log n).
+//
+// Every pushed item returns a [HeapHandle] which can be used to update or remove
+// that item later regardless of its position.
+type Heap[Type any] struct {
+	lock  sync.RWMutex
+	items heapItems[Type]
+}
+
+// Len returns the number of items in the heap. This is thread-safe.
+func (h *Heap[Type]) Len() int {
+	h.lock.RLock()
+	defer h.lock.RUnlock()
+
+	return len(h.items.items)
+}
+
+// Push adds the item to the heap and returns a handle to it.
+func (h *Heap[Type]) Push(value Type) *HeapHandle[Type] {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	handle := &HeapHandle[Type]{
+		heap:  h,
+		value: value,
+	}
+	heap.Push(&h.items, handle)
+	return handle
+}
+
+// Peek returns the first item without removing it. If the heap is empty it
+// returns false.
+func (h *Heap[Type]) Peek() (Type, bool) {
+	h.lock.RLock()
+	defer h.lock.RUnlock()
+
+	if len(h.items.items) == 0 {
+		return MakeAny[Type](), false
+	}
+	return h.items.items[0].value, true
+}
+
+// Pop removes and returns the first item. If the heap is empty it returns false.
+func (h *Heap[Type]) Pop() (Type, bool) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	if len(h.items.items) == 0 {
+		return MakeAny[Type](), false
+	}
+	return heap.Pop(&h.items).(*HeapHandle[Type]).value, true
+}
+
+// Update replaces the value of the item and moves it to its new position. It
+// returns false if the item is no longer in the heap.
+func (h *Heap[Type]) Update(handle *HeapHandle[Type], value Type) bool {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	if !h.owns(handle) {
+		return false
+	}
+
+	handle.value = value
+	heap.Fix(&h.items, handle.index)
+	return true
+}
+
+// Remove takes the item out of the heap. It returns false if the item is no
+// longer in the heap.
+func (h *Heap[Type]) Remove(handle *HeapHandle[Type]) bool {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	if !h.owns(handle) {
+		return false
+	}
+
+	heap.Remove(&h.items, handle.index)
+	return true
+}
+
+// Clear removes every item from the heap.
+func (h *Heap[Type]) Clear() {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	for _, item := range h.items.items {
+		item.index = -1
+	}
+	h.items.items = nil
+}
+
+// owns returns true if the handle is currently in this heap. The lock must be
+// held by the caller.
+func (h *Heap[Type]) owns(handle *HeapHandle[Type]) bool {
+	return handle != nil && handle.heap == h && handle.index >= 0 && handle.index < len(h.items.items) && h.items.items[handle.index] == handle
+}
+
+// NewHeap constructs a new [Heap] ordered by the less function, which returns
+// true if a should be popped before b.
+func NewHeap[Type any](less func(a, b Type) bool) Heap[Type] {
+	return Heap[Type]{
+		items: heapItems[Type]{
+			less: less,
+		},
+	}
+}
+
+// NewMinHeap constructs a new [Heap] which pops the smallest number first.
+func NewMinHeap[Type Number]() Heap[Type] {
+	return NewHeap(func(a, b Type) bool {
+		return a < b
+	})
+}
+
+// NewMaxHeap constructs a new [Heap] which pops the largest number first.
+func NewMaxHeap[Type Number]() Heap[Type] {
+	return NewHeap(func(a, b Type) bool {
+		return a > b
+	})
+}
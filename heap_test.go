@@ -0,0 +1,61 @@
+package gox
+
+import "testing"
+
+func TestHeapOrder(t *testing.T) {
+	h := NewMinHeap[int]()
+
+	for _, v := range []int{5, 1, 4, 2, 3} {
+		h.Push(v)
+	}
+
+	if v, ok := h.Peek(); !ok || v != 1 {
+		t.Error("incorrect peek", v)
+	}
+
+	for i := 1; i <= 5; i++ {
+		if v, ok := h.Pop(); !ok || v != i {
+			t.Error("popped out of order", v, i)
+		}
+	}
+
+	if _, ok := h.Pop(); ok {
+		t.Error("popped from empty heap")
+	}
+}
+
+func TestHeapUpdateRemove(t *testing.T) {
+	type job struct {
+		name     string
+		priority int
+	}
+
+	h := NewHeap(func(a, b job) bool {
+		return a.priority > b.priority
+	})
+
+	low := h.Push(job{"low", 1})
+	mid := h.Push(job{"mid", 5})
+	h.Push(job{"high", 10})
+
+	if !h.Update(low, job{"low", 20}) {
+		t.Error("update failed")
+	}
+	if !h.Remove(mid) || h.Remove(mid) {
+		t.Error("remove did not work once")
+	}
+	if h.Len() != 2 {
+		t.Error("incorrect length", h.Len())
+	}
+
+	if v, _ := h.Pop(); v.name != "low" {
+		t.Error("update did not reorder", v)
+	}
+	if v, _ := h.Pop(); v.name != "high" {
+		t.Error("incorrect remaining item", v)
+	}
+
+	if h.Update(low, job{}) {
+		t.Error("updated a popped item")
+	}
+}
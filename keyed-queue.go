@@ -0,0 +1,170 @@
+package gox
+
+import "sync"
+
+// DuplicatePolicy declares what a [KeyedQueue] does when an item is pushed with
+// a key that is already queued.
+type DuplicatePolicy uint8
+
+const (
+	// DuplicateReplace replaces the queued item with the new one, keeping its
+	// place in the queue.
+	DuplicateReplace DuplicatePolicy = iota
+
+	// DuplicateIgnore keeps the queued item and discards the new one.
+	DuplicateIgnore
+)
+
+// KeyedQueue is a variant of [PriorityQueue] where every item has a key, and
+// only one item per key may be queued at a time. Pushing an item whose key is
+// already queued either replaces it in place or is ignored, depending on the
+// [DuplicatePolicy]. This collapses bursts of the same work into a single item.
+//
+// Like [PriorityQueue] it is FIFO, has a limited capacity that drops the oldest
+// items when exceeded, and is thread-safe.
+type KeyedQueue[Key comparable, Type any] struct {
+	capacity int
+	policy   DuplicatePolicy
+	keys     ringBuffer[Key]
+	items    map[Key]Type
+	lock     sync.RWMutex
+}
+
+// Capacity returns the maximum capacity of the queue
+func (q *KeyedQueue[Key, Type]) Capacity() int {
+	return q.capacity
+}
+
+// Length returns the current number of items in the queue. This is thread
+// safe.
+func (q *KeyedQueue[Key, Type]) Length() int {
+	q.lock.RLock()
+	defer q.lock.RUnlock()
+
+	return q.keys.count
+}
+
+// Contains returns true if an item with the key is queued.
+func (q *KeyedQueue[Key, Type]) Contains(key Key) bool {
+	q.lock.RLock()
+	defer q.lock.RUnlock()
+
+	_, ok := q.items[key]
+	return ok
+}
+
+// Keys returns the keys of the queued items, oldest first.
+func (q *KeyedQueue[Key, Type]) Keys() []Key {
+	q.lock.RLock()
+	defer q.lock.RUnlock()
+
+	keys := make([]Key, q.keys.count)
+	q.keys.read(keys)
+	return keys
+}
+
+// Slice returns a copy of the queued items, oldest first. This is thread-safe.
+func (q *KeyedQueue[Key, Type]) Slice() []Type {
+	return q.Peek(q.capacity)
+}
+
+// Peek returns the elements (up to [count] number) of the oldest elements in
+// the queue. This is thread-safe.
+func (q *KeyedQueue[Key, Type]) Peek(count int) []Type {
+	q.lock.RLock()
+	defer q.lock.RUnlock()
+
+	arr := make([]Type, Max(Min(count, q.keys.count), 0))
+	for i := range arr {
+		arr[i] = q.items[q.keys.at(i)]
+	}
+	return arr
+}
+
+// Pop returns the elements (up to [count] number) of the oldest elements in
+// the queue, removing them from the queue. Their keys may then be pushed again.
+func (q *KeyedQueue[Key, Type]) Pop(count int) []Type {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	arr := make([]Type, Max(Min(count, q.keys.count), 0))
+	for i := range arr {
+		key := q.keys.at(i)
+		arr[i] = q.items[key]
+		delete(q.items, key)
+	}
+	q.keys.discard(len(arr))
+	return arr
+}
+
+// Push adds the item to the end of the queue under the given key. If the key is
+// already queued the [DuplicatePolicy] decides what happens and added is false.
+// If the capacity is exceeded the oldest item is dropped to make room, in which
+// case displaced is 1.
+func (q *KeyedQueue[Key, Type]) Push(key Key, item Type) (added bool, displaced int) {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	if q.items == nil {
+		q.items = make(map[Key]Type)
+	}
+
+	if _, ok := q.items[key]; ok {
+		if q.policy == DuplicateReplace {
+			q.items[key] = item
+		}
+		return false, 0
+	}
+
+	if q.capacity <= 0 {
+		return false, 0
+	}
+
+	if q.keys.free() == 0 {
+		delete(q.items, q.keys.at(0))
+		q.keys.discard(1)
+		displaced = 1
+	}
+
+	q.keys.push(key)
+	q.items[key] = item
+	return true, displaced
+}
+
+// Remove takes the item with the key out of the queue. It returns false if the
+// key was not queued.
+func (q *KeyedQueue[Key, Type]) Remove(key Key) bool {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	if _, ok := q.items[key]; !ok {
+		return false
+	}
+	delete(q.items, key)
+
+	// Shift everything after the key down to close the gap
+	found := false
+	for i := 0; i < q.keys.count-1; i++ {
+		if !found && q.keys.at(i) == key {
+			found = true
+		}
+		if found {
+			q.keys.set(i, q.keys.at(i+1))
+		}
+	}
+	q.keys.popBack()
+	return true
+}
+
+// NewKeyedQueue constructs a new [KeyedQueue] using the given capacity and
+// policy for duplicate keys.
+func NewKeyedQueue[Key comparable, Type any](capacity int, policy DuplicatePolicy) KeyedQueue[Key, Type] {
+	capacity = Max(capacity, 1)
+
+	return KeyedQueue[Key, Type]{
+		capacity: capacity,
+		policy:   policy,
+		keys:     newRingBuffer[Key](capacity),
+		items:    make(map[Key]Type, capacity),
+	}
+}
@@ -0,0 +1,45 @@
+package gox
+
+import "testing"
+
+func TestKeyedQueue(t *testing.T) {
+	q := NewKeyedQueue[string, int](3, DuplicateReplace)
+
+	q.Push("a", 1)
+	q.Push("b", 2)
+	if added, _ := q.Push("a", 10); added {
+		t.Error("duplicate key was added")
+	}
+	if items := q.Slice(); len(items) != 2 || items[0] != 10 || items[1] != 2 {
+		t.Error("duplicate was not replaced in place", items)
+	}
+
+	q.Push("c", 3)
+	if added, disp := q.Push("d", 4); !added || disp != 1 {
+		t.Error("oldest was not displaced", added, disp)
+	}
+	if q.Contains("a") {
+		t.Error("displaced key still queued")
+	}
+
+	if !q.Remove("c") || q.Remove("c") {
+		t.Error("remove did not work once")
+	}
+	if keys := q.Keys(); len(keys) != 2 || keys[0] != "b" || keys[1] != "d" {
+		t.Error("incorrect keys after remove", keys)
+	}
+
+	if items := q.Pop(5); len(items) != 2 || items[0] != 2 || items[1] != 4 {
+		t.Error("incorrect popped items", items)
+	}
+	if added, _ := q.Push("b", 5); !added {
+		t.Error("popped key could not be pushed again")
+	}
+
+	ignore := NewKeyedQueue[string, int](3, DuplicateIgnore)
+	ignore.Push("a", 1)
+	ignore.Push("a", 2)
+	if items := ignore.Slice(); len(items) != 1 || items[0] != 1 {
+		t.Error("duplicate was not ignored", items)
+	}
+}
@@ -0,0 +1,86 @@
+package gox
+
+import "sync"
+
+// Change describes a value of an [Observable] being replaced.
+type Change[T any] struct {
+	Old T
+	New T
+}
+
+// Observable holds a value behind a lock and notifies listeners with a [Change]
+// every time it is set. If an equality function is provided then setting a value
+// equal to the current one does not notify anyone.
+//
+// Listeners are called after the lock is released so they may read or even set
+// the value themselves. When setting from several goroutines at once the
+// notifications may arrive out of order, but each [Change] is consistent.
+type Observable[T any] struct {
+	lock   sync.RWMutex
+	value  T
+	equal  func(a, b T) bool
+	events EventManager[Change[T]]
+}
+
+// Get returns the current value.
+func (o *Observable[T]) Get() T {
+	o.lock.RLock()
+	defer o.lock.RUnlock()
+
+	return o.value
+}
+
+// Set replaces the value and notifies the listeners. It returns the errors of
+// the dispatch, see [EventManager.Dispatch].
+func (o *Observable[T]) Set(value T) error {
+	return o.Update(func(T) T {
+		return value
+	})
+}
+
+// Update replaces the value with the result of the function, which is called
+// with the current value while holding the lock. This makes read-modify-write
+// operations atomic. The function must not access the observable itself.
+func (o *Observable[T]) Update(fn func(current T) T) error {
+	o.lock.Lock()
+	change := Change[T]{
+		Old: o.value,
+		New: fn(o.value),
+	}
+	if o.equal != nil && o.equal(change.Old, change.New) {
+		o.lock.Unlock()
+		return nil
+	}
+	o.value = change.New
+	o.lock.Unlock()
+
+	return o.events.Dispatch(change)
+}
+
+// OnChange adds a listener for changes to the value. If the callback returns
+// true it is removed, the same as [EventManager.Add].
+func (o *Observable[T]) OnChange(cb func(change Change[T]) bool) uint {
+	return o.events.Add(cb)
+}
+
+// Remove removes the change listener with the given ID.
+func (o *Observable[T]) Remove(id uint) {
+	o.events.Remove(id)
+}
+
+// Events returns the underlying [EventManager] used to publish changes.
+func (o *Observable[T]) Events() *EventManager[Change[T]] {
+	return &o.events
+}
+
+// NewObservable creates a new [Observable] holding the initial value. The
+// equality function is optional, when given it suppresses notifications for
+// values equal to the current one. The options are applied to the underlying
+// [EventManager].
+func NewObservable[T any](initial T, equal func(a, b T) bool, opts ...EventOption) Observable[T] {
+	return Observable[T]{
+		value:  initial,
+		equal:  equal,
+		events: NewEventManager[Change[T]](opts...),
+	}
+}
@@ -0,0 +1,30 @@
+package gox
+
+import "testing"
+
+func TestObservable(t *testing.T) {
+	o := NewObservable(1, func(a, b int) bool {
+		return a == b
+	})
+
+	changes := []Change[int]{}
+	o.OnChange(func(change Change[int]) bool {
+		changes = append(changes, change)
+		return false
+	})
+
+	o.Set(2)
+	o.Set(2)
+	o.Update(func(current int) int {
+		return current * 10
+	})
+
+	if o.Get() != 20 {
+		t.Error("incorrect value", o.Get())
+	}
+	if len(changes) != 2 {
+		t.Error("equal value was not suppressed", changes)
+	} else if changes[0].Old != 1 || changes[0].New != 2 || changes[1].Old != 2 || changes[1].New != 20 {
+		t.Error("incorrect changes", changes)
+	}
+}
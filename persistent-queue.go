@@ -0,0 +1,389 @@
+package gox
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ErrQueueClosed is returned when using a [PersistentQueue] after it is closed.
+var ErrQueueClosed = errors.New("queue is closed")
+
+// QueueCodec encodes and decodes the items of a [PersistentQueue] to bytes.
+type QueueCodec[Type any] interface {
+	Encode(item Type) ([]byte, error)
+	Decode(data []byte) (Type, error)
+}
+
+// JSONCodec is a [QueueCodec] using the library-wide [JSONMarshaler] and
+// [JSONUnmarshaler].
+type JSONCodec[Type any] struct{}
+
+func (JSONCodec[Type]) Encode(item Type) ([]byte, error) {
+	return JSONMarshaler(item)
+}
+
+func (JSONCodec[Type]) Decode(data []byte) (item Type, err error) {
+	err = JSONUnmarshaler(data, &item)
+	return
+}
+
+const (
+	persistentSegmentExt = ".seg"
+	persistentCursorFile = "cursor"
+
+	// maxPersistentRecord limits the size of a single encoded item read from a
+	// segment to guard against corrupt length prefixes.
+	maxPersistentRecord = 64 << 20
+)
+
+// persistentSegment is a file of items spilled to disk.
+type persistentSegment struct {
+	id    uint64
+	count int
+}
+
+// persistentCursor records how far into the oldest segment has been consumed.
+type persistentCursor struct {
+	Segment uint64 `json:"segment"`
+	Offset  int    `json:"offset"`
+}
+
+// PersistentQueue is a durable FIFO queue which keeps its newest items in memory
+// like [PriorityQueue], but spills them to segment files in a directory once
+// more than the memory budget of items are held. Reopening the directory
+// recovers the queued items, so they survive a restart.
+//
+// Items still in memory are only written to disk by [Flush] or [Close], or when
+// the budget is exceeded. Popping from a segment records the position in a
+// cursor file so that consumed items are not returned again after a restart.
+//
+// Unlike [PriorityQueue] it has no capacity and never drops items. It is
+// thread-safe.
+type PersistentQueue[Type any] struct {
+	lock     sync.Mutex
+	dir      string
+	budget   int
+	codec    QueueCodec[Type]
+	head     []Type
+	headSeg  uint64
+	headOff  int
+	segments []persistentSegment
+	tail     []Type
+	nextSeg  uint64
+	closed   bool
+}
+
+func (q *PersistentQueue[Type]) segmentPath(id uint64) string {
+	return filepath.Join(q.dir, fmt.Sprintf("%020d%s", id, persistentSegmentExt))
+}
+
+// writeFile atomically replaces the named file in the queue directory.
+func (q *PersistentQueue[Type]) writeFile(path string, write func(w io.Writer) error) error {
+	tmp, err := os.CreateTemp(q.dir, ".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	buf := bufio.NewWriter(tmp)
+	if err = write(buf); err == nil {
+		if err = buf.Flush(); err == nil {
+			err = tmp.Sync()
+		}
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
+
+// spill writes the in-memory items to a new segment. The lock must be held by
+// the caller.
+func (q *PersistentQueue[Type]) spill() error {
+	if len(q.tail) == 0 {
+		return nil
+	}
+
+	id := q.nextSeg
+	err := q.writeFile(q.segmentPath(id), func(w io.Writer) error {
+		var header [binary.MaxVarintLen64]byte
+		for _, item := range q.tail {
+			data, err := q.codec.Encode(item)
+			if err != nil {
+				return err
+			}
+
+			n := binary.PutUvarint(header[:], uint64(len(data)))
+			if _, err = w.Write(header[:n]); err != nil {
+				return err
+			}
+			if _, err = w.Write(data); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	q.nextSeg++
+	q.segments = append(q.segments, persistentSegment{id: id, count: len(q.tail)})
+	q.tail = nil
+	return nil
+}
+
+// readSegment reads the records of a segment file, decoding them if decode is
+// true, otherwise only counting them.
+func (q *PersistentQueue[Type]) readSegment(id uint64, decode bool) (items []Type, count int, err error) {
+	f, err := os.Open(q.segmentPath(id))
+	if err != nil {
+		return nil, 0, err
+	}
+	defer f.Close()
+
+	r := bufio.NewReader(f)
+	for {
+		size, err := binary.ReadUvarint(r)
+		if err == io.EOF {
+			return items, count, nil
+		} else if err != nil {
+			return nil, 0, err
+		} else if size > maxPersistentRecord {
+			return nil, 0, fmt.Errorf("queue segment record of %d bytes exceeds limit", size)
+		}
+
+		data := make([]byte, size)
+		if _, err = io.ReadFull(r, data); err != nil {
+			return nil, 0, err
+		}
+		count++
+
+		if decode {
+			item, err := q.codec.Decode(data)
+			if err != nil {
+				return nil, 0, err
+			}
+			items = append(items, item)
+		}
+	}
+}
+
+// saveCursor records the consumed position of the head segment. The lock must
+// be held by the caller.
+func (q *PersistentQueue[Type]) saveCursor() error {
+	data, err := JSONMarshaler(persistentCursor{Segment: q.headSeg, Offset: q.headOff})
+	if err != nil {
+		return err
+	}
+
+	return q.writeFile(filepath.Join(q.dir, persistentCursorFile), func(w io.Writer) error {
+		_, err := w.Write(data)
+		return err
+	})
+}
+
+// Length returns the number of queued items, both in memory and on disk.
+func (q *PersistentQueue[Type]) Length() int {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	length := len(q.head) + len(q.tail)
+	for _, seg := range q.segments {
+		length += seg.count
+	}
+	return length
+}
+
+// Push adds items to the end of the queue. If this takes the items held in
+// memory over the budget they are spilled to a new segment file.
+func (q *PersistentQueue[Type]) Push(items ...Type) error {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	if q.closed {
+		return ErrQueueClosed
+	}
+
+	q.tail = append(q.tail, items...)
+	if len(q.tail) > q.budget {
+		return q.spill()
+	}
+	return nil
+}
+
+// Pop returns the elements (up to [count] number) of the oldest elements in
+// the queue, removing them from the queue.
+func (q *PersistentQueue[Type]) Pop(count int) ([]Type, error) {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	if q.closed {
+		return nil, ErrQueueClosed
+	}
+
+	arr := make([]Type, 0, Max(count, 0))
+	moved := false
+	for len(arr) < count {
+		if len(q.head) > 0 {
+			n := Min(count-len(arr), len(q.head))
+			arr = append(arr, q.head[:n]...)
+			q.head = q.head[n:]
+			q.headOff += n
+			moved = true
+			continue
+		}
+
+		if q.headSeg != 0 {
+			// Head segment is used up
+			if err := os.Remove(q.segmentPath(q.headSeg)); err != nil && !os.IsNotExist(err) {
+				return arr, err
+			}
+			q.headSeg, q.headOff = 0, 0
+			moved = true
+		}
+
+		if len(q.segments) > 0 {
+			seg := q.segments[0]
+			items, _, err := q.readSegment(seg.id, true)
+			if err != nil {
+				return arr, err
+			}
+
+			// Skip whatever was consumed before a restart
+			skip := len(items) - seg.count
+			if skip < 0 {
+				return arr, fmt.Errorf("queue segment %d has %d items, expected at least %d", seg.id, len(items), seg.count)
+			}
+			q.head = items[skip:]
+			q.headSeg, q.headOff = seg.id, skip
+			q.segments = q.segments[1:]
+			continue
+		}
+
+		n := Min(count-len(arr), len(q.tail))
+		arr = append(arr, q.tail[:n]...)
+		q.tail = q.tail[n:]
+		break
+	}
+
+	if moved {
+		if err := q.saveCursor(); err != nil {
+			return arr, err
+		}
+	}
+	return arr, nil
+}
+
+// Flush writes any items held in memory to a segment file so that they survive
+// a restart.
+func (q *PersistentQueue[Type]) Flush() error {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	if q.closed {
+		return ErrQueueClosed
+	}
+	return q.spill()
+}
+
+// Close flushes the items held in memory to disk and closes the queue. The
+// queue can be recovered by opening the same directory again.
+func (q *PersistentQueue[Type]) Close() error {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	if q.closed {
+		return nil
+	}
+	q.closed = true
+
+	if err := q.spill(); err != nil {
+		return err
+	}
+
+	// The unconsumed head remains in its segment, tracked by the cursor. Without
+	// a head the cursor recovered on open has not moved, and must be kept.
+	if q.headSeg == 0 {
+		return nil
+	}
+	return q.saveCursor()
+}
+
+// OpenPersistentQueue opens, or creates, a [PersistentQueue] storing its
+// segments in the given directory. Up to memoryItems items are held in memory
+// before spilling to disk. If codec is nil then [JSONCodec] is used.
+func OpenPersistentQueue[Type any](dir string, memoryItems int, codec QueueCodec[Type]) (*PersistentQueue[Type], error) {
+	if codec == nil {
+		codec = JSONCodec[Type]{}
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+
+	q := &PersistentQueue[Type]{
+		dir:     dir,
+		budget:  Max(memoryItems, 1),
+		codec:   codec,
+		nextSeg: 1,
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var ids []uint64
+	for _, entry := range entries {
+		name, ok := strings.CutSuffix(entry.Name(), persistentSegmentExt)
+		if !ok || entry.IsDir() {
+			continue
+		}
+		if id, err := strconv.ParseUint(name, 10, 64); err == nil {
+			ids = append(ids, id)
+		}
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		return ids[i] < ids[j]
+	})
+
+	var cursor persistentCursor
+	if data, err := os.ReadFile(filepath.Join(dir, persistentCursorFile)); err == nil {
+		if err = JSONUnmarshaler(data, &cursor); err != nil {
+			return nil, fmt.Errorf("reading queue cursor: %w", err)
+		}
+	} else if !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	// Never reuse the ID the cursor refers to, even if its segment is gone
+	q.nextSeg = Max(q.nextSeg, cursor.Segment+1)
+
+	for _, id := range ids {
+		_, count, err := q.readSegment(id, false)
+		if err != nil {
+			return nil, fmt.Errorf("reading queue segment %d: %w", id, err)
+		}
+		if id == cursor.Segment {
+			count -= Min(cursor.Offset, count)
+		}
+
+		q.segments = append(q.segments, persistentSegment{id: id, count: count})
+		q.nextSeg = Max(q.nextSeg, id+1)
+	}
+
+	return q, nil
+}
@@ -0,0 +1,135 @@
+package gox
+
+import (
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestPersistentQueueRecovery(t *testing.T) {
+	dir := t.TempDir()
+
+	q, err := OpenPersistentQueue[int](dir, 3, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 10; i++ {
+		if err := q.Push(i); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if q.Length() != 10 {
+		t.Error("incorrect length", q.Length())
+	}
+
+	items, err := q.Pop(3)
+	if err != nil || len(items) != 3 || items[0] != 0 || items[2] != 2 {
+		t.Error("incorrect popped items", items, err)
+	}
+	if err := q.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := q.Push(1); err != ErrQueueClosed {
+		t.Error("pushed to a closed queue", err)
+	}
+
+	q, err = OpenPersistentQueue[int](dir, 3, JSONCodec[int]{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if q.Length() != 7 {
+		t.Error("incorrect length after reopening", q.Length())
+	}
+
+	q.Push(10)
+	items, err = q.Pop(100)
+	if err != nil || len(items) != 8 {
+		t.Fatal("incorrect items after reopening", items, err)
+	}
+	for i, v := range items {
+		if v != i+3 {
+			t.Error("items out of order", items)
+			break
+		}
+	}
+	q.Close()
+
+	q, err = OpenPersistentQueue[int](dir, 3, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if q.Length() != 0 {
+		t.Error("consumed items came back", q.Length())
+	}
+	q.Push(20)
+	q.Close()
+
+	q, _ = OpenPersistentQueue[int](dir, 3, nil)
+	if items, _ := q.Pop(1); len(items) != 1 || items[0] != 20 {
+		t.Error("new segment was not recovered", items)
+	}
+}
+
+func TestPersistentQueueReopenTwice(t *testing.T) {
+	dir := t.TempDir()
+
+	q, err := OpenPersistentQueue[int](dir, 3, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 10; i++ {
+		q.Push(i)
+	}
+	q.Pop(3)
+	q.Close()
+
+	// Closing again without popping must keep the consumed position
+	for i := 0; i < 2; i++ {
+		if q, err = OpenPersistentQueue[int](dir, 3, nil); err != nil {
+			t.Fatal(err)
+		}
+		if q.Length() != 7 {
+			t.Error("incorrect length after reopening", i, q.Length())
+		}
+		if i == 0 {
+			q.Close()
+		}
+	}
+
+	items, err := q.Pop(100)
+	if err != nil || len(items) != 7 || items[0] != 3 {
+		t.Error("consumed items came back", items, err)
+	}
+	q.Close()
+}
+
+func TestPersistentQueueCorruptSegment(t *testing.T) {
+	dir := t.TempDir()
+
+	q, err := OpenPersistentQueue[int](dir, 1, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	q.Push(1, 2, 3)
+
+	// Truncate the segment behind the queue's back
+	if err := os.WriteFile(q.segmentPath(1), []byte{1, '4'}, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := q.Pop(1); err == nil {
+		t.Error("short segment was not reported")
+	}
+	q.Close()
+
+	// A length prefix far beyond the limit
+	var header [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(header[:], 1<<62)
+	if err := os.WriteFile(filepath.Join(dir, "00000000000000000099.seg"), header[:n], 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := OpenPersistentQueue[int](dir, 1, nil); err == nil {
+		t.Error("oversized record was not reported")
+	}
+}
@@ -1,112 +1,458 @@
 package gox
 
-import "sync"
-
-// PriorityQueue is a queue type backed by a slice and mutex locks that takes
-// new items in and adds them to the end of the queue. It features a limited size
-// that when exceeded will trim the contents and shift the queue to make room
-// for new items. I didn't have a better name, but basically it "prioritizes"
-// incoming items over older indices by shifting the slice left. It is considered
-// FIFO in that the oldest items are returned first when accessing.
+import (
+	"bytes"
+	"context"
+	"encoding/gob"
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrQueueFull is returned when pushing to a queue using [OverflowError] that
+// does not have room for all the items.
+var ErrQueueFull = errors.New("queue is full")
+
+// OverflowPolicy declares what a [PriorityQueue] does when items are pushed
+// beyond its capacity.
+type OverflowPolicy uint8
+
+const (
+	// OverflowDropOldest drops the oldest items in the queue to make room for
+	// the new ones. This is the default.
+	OverflowDropOldest OverflowPolicy = iota
+
+	// OverflowRejectNewest keeps the queue as it is and drops the pushed items
+	// that do not fit.
+	OverflowRejectNewest
+
+	// OverflowBlock waits for items to be popped to make room for the new ones.
+	OverflowBlock
+
+	// OverflowError pushes nothing and returns [ErrQueueFull] unless all the
+	// pushed items fit, rejecting all of them.
+	OverflowError
+)
+
+// PriorityQueue is a queue type backed by a circular buffer and mutex locks
+// that takes new items in and adds them to the end of the queue. It features a
+// limited size that when exceeded will drop the oldest items to make room for
+// new items. I didn't have a better name, but basically it "prioritizes"
+// incoming items over older ones. It is considered FIFO in that the oldest items
+// are returned first when accessing.
+//
+// Pushing and popping are constant time per item and do not allocate once the
+// queue has been created, except for the slices returned to the caller.
+//
+// All methods are safe to call from multiple goroutines. Methods modifying the
+// queue hold the lock exclusively, while those only reading it may run at the
+// same time as each other.
 type PriorityQueue[Type any] struct {
 	capacity int
-	items    []Type
+	ring     ringBuffer[Type]
 	lock     sync.RWMutex
+	changed  chan struct{}
+	policy   OverflowPolicy
+	onEvict  func(evicted []Type)
 }
 
-// Capacity returns the maximum capacity of the slice
+// Capacity returns the maximum capacity of the queue. This is thread safe, as
+// the capacity changes when a snapshot is unmarshalled into the queue.
 func (q *PriorityQueue[Type]) Capacity() int {
+	q.lock.RLock()
+	defer q.lock.RUnlock()
+
 	return q.capacity
 }
 
-// Length returns the current length of the internal queue slice. This is thread
+// Length returns the current number of items in the queue. This is thread
 // safe.
 func (q *PriorityQueue[Type]) Length() int {
-	q.lock.Lock()
-	length := len(q.items)
-	q.lock.Unlock()
+	q.lock.RLock()
+	length := q.ring.count
+	q.lock.RUnlock()
 
 	return length
 }
 
-// Slice returns a copy of the internal slice. This is thread-safe.
+// Slice returns a copy of the queue contents, oldest first. This is
+// thread-safe.
 func (q *PriorityQueue[Type]) Slice() []Type {
-	q.lock.Lock()
-	arr := CopySlice(q.items)
-	q.lock.Unlock()
+	q.lock.RLock()
+	arr := make([]Type, q.ring.count)
+	q.ring.read(arr)
+	q.lock.RUnlock()
 
 	return arr
 }
 
 // Peek returns the elements (up to [count] number) of the oldest elements in
-// the queue.
+// the queue. This is thread-safe.
 func (q *PriorityQueue[Type]) Peek(count int) []Type {
-	q.lock.Lock()
-	arr := PeekSliceOldest(q.items, count)
-	q.lock.Unlock()
+	q.lock.RLock()
+	arr := make([]Type, Max(Min(count, q.ring.count), 0))
+	q.ring.read(arr)
+	q.lock.RUnlock()
 
 	return arr
 }
 
 // Pop returns the elements (up to [count] number) of the oldest elements in
-// the queue. It adjusts the queue by removing the items chosen and shifting the
-// newer ones down.
+// the queue, removing them from the queue.
 func (q *PriorityQueue[Type]) Pop(count int) []Type {
-	q.lock.RLock()
-	defer q.lock.RUnlock()
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	return q.popLocked(count)
+}
+
+// popLocked removes up to count of the oldest items and wakes anyone waiting
+// for space. The lock must be held exclusively by the caller.
+func (q *PriorityQueue[Type]) popLocked(count int) []Type {
+	arr := make([]Type, Max(Min(count, q.ring.count), 0))
+	q.ring.discard(q.ring.read(arr))
+	if len(arr) > 0 {
+		q.notifyChanged()
+	}
+	return arr
+}
+
+// PopInto fills the given slice with the oldest elements in the queue, removing
+// them from the queue. It returns the number of elements popped. Unlike [Pop]
+// this does not allocate, so the same slice can be reused.
+func (q *PriorityQueue[Type]) PopInto(dst []Type) int {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	count := q.ring.read(dst)
+	q.ring.discard(count)
+	if count > 0 {
+		q.notifyChanged()
+	}
+	return count
+}
+
+// changedSignal returns a channel which is closed the next time items are
+// pushed or popped. The lock must be held exclusively by the caller.
+func (q *PriorityQueue[Type]) changedSignal() <-chan struct{} {
+	if q.changed == nil {
+		q.changed = make(chan struct{})
+	}
+	return q.changed
+}
 
-	var items []Type
-	q.items, items = ShiftMultipleFromSlice(q.items, count)
-	return items
+// notifyChanged wakes anyone waiting for items or space. The lock must be held
+// exclusively by the caller.
+func (q *PriorityQueue[Type]) notifyChanged() {
+	if q.changed != nil {
+		close(q.changed)
+		q.changed = nil
+	}
 }
 
-// Push adds items to the end of the queue. If the capacity is exceeded it shifts
-// the oldest items out to make room for the new items. It returns the number
-// of displaced items (if any). If more items are pushed than there is capacity
-// for, then the items being pushed are also trimmed to the [Capacity] limit of
-// oldest items.
+// PopWait is the same as [Pop] except that it blocks until at least one item is
+// available. If the context is done first it returns the context error. A count
+// of less than 1 is treated as 1.
+func (q *PriorityQueue[Type]) PopWait(ctx context.Context, count int) ([]Type, error) {
+	count = Max(count, 1)
+
+	for {
+		q.lock.Lock()
+		if q.ring.count > 0 {
+			arr := q.popLocked(count)
+			q.lock.Unlock()
+			return arr, nil
+		}
+		wait := q.changedSignal()
+		q.lock.Unlock()
+
+		select {
+		case <-wait:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// PopBatch waits until size items are in the queue and pops them. If maxWait
+// passes first then whatever items are available are popped instead, which may
+// be none. If the context is done first the available items are popped and
+// returned along with the context error. The size is limited to the [Capacity],
+// and a size of less than 1 is treated as 1.
+//
+// This suits batching consumers, such as bulk writers, which would rather wait
+// for a full batch but must not hold items for too long.
+func (q *PriorityQueue[Type]) PopBatch(ctx context.Context, size int, maxWait time.Duration) ([]Type, error) {
+	size = Max(size, 1)
+
+	timer := time.NewTimer(maxWait)
+	defer timer.Stop()
+
+	for {
+		q.lock.Lock()
+		if q.ring.count >= Min(size, q.capacity) {
+			arr := q.popLocked(size)
+			q.lock.Unlock()
+			return arr, nil
+		}
+		wait := q.changedSignal()
+		q.lock.Unlock()
+
+		select {
+		case <-wait:
+		case <-timer.C:
+			return q.Pop(size), nil
+		case <-ctx.Done():
+			return q.Pop(size), ctx.Err()
+		}
+	}
+}
+
+// SetOverflowPolicy changes how the queue handles items pushed beyond its
+// capacity. See [OverflowPolicy] for the choices.
+func (q *PriorityQueue[Type]) SetOverflowPolicy(policy OverflowPolicy) {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	q.policy = policy
+}
+
+// SetEvictionCallback sets a function to receive the items dropped by a push,
+// whether they are old items displaced by [OverflowDropOldest] or new items
+// turned away by [OverflowRejectNewest], [OverflowError] or a cancelled
+// [OverflowBlock]. The slice is not shared with the caller of the push. It is
+// called after the lock is released, so it may use the queue. Pass nil to
+// remove it.
+func (q *PriorityQueue[Type]) SetEvictionCallback(cb func(evicted []Type)) {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	q.onEvict = cb
+}
+
+// Push adds items to the end of the queue. If the capacity is exceeded then the
+// [OverflowPolicy] decides what happens. It returns the number of displaced (or
+// rejected) items if any.
+//
+// With [OverflowDropOldest], the default, the oldest items are dropped to make
+// room for the new items. If more items are pushed than there is capacity for,
+// then the items being pushed are also trimmed to the [Capacity] limit of
+// newest items.
+//
+// With [OverflowRejectNewest] as many of the items as fit are pushed, keeping
+// the first of them, and the rest are rejected.
+//
+// With [OverflowBlock] this waits as long as it takes for room to be made, see
+// [PushContext] to limit that. The items are pushed in chunks as room becomes
+// available, so when more are pushed than there is capacity for the push is not
+// atomic: the first items can be popped before the rest have been pushed.
+//
+// With [OverflowError] nothing is pushed if the items do not all fit, and all
+// of them are counted as rejected. The error is discarded, see [PushContext] to
+// receive it.
 func (q *PriorityQueue[Type]) Push(items ...Type) int {
-	q.lock.RLock()
-	defer q.lock.RUnlock()
+	disp, _ := q.PushContext(context.Background(), items...)
+	return disp
+}
+
+// PushContext is the same as [Push] but also returns [ErrQueueFull] for the
+// [OverflowError] policy, and when using [OverflowBlock] stops waiting once the
+// context is done. In that case the items not yet pushed are rejected and the
+// context error is returned.
+func (q *PriorityQueue[Type]) PushContext(ctx context.Context, items ...Type) (int, error) {
+	q.lock.Lock()
+
+	if q.capacity <= 0 || len(items) == 0 {
+		q.lock.Unlock()
+		return 0, nil
+	}
+
+	var disp int
+	var evicted []Type
+	var err error
+
+	switch q.policy {
+	case OverflowRejectNewest:
+		fits := Min(len(items), q.ring.free())
+		q.ring.push(items[:fits]...)
+		disp = len(items) - fits
+		if q.onEvict != nil && disp > 0 {
+			// Copy so the callback never holds on to the caller's slice
+			evicted = CopySlice(items[fits:])
+		}
+	case OverflowError:
+		if len(items) > q.ring.free() {
+			err = ErrQueueFull
+			disp = len(items)
+			if q.onEvict != nil {
+				evicted = CopySlice(items)
+			}
+		} else {
+			q.ring.push(items...)
+		}
+	case OverflowBlock:
+		for len(items) > 0 {
+			fits := Min(len(items), q.ring.free())
+			if fits > 0 {
+				q.ring.push(items[:fits]...)
+				items = items[fits:]
+				q.notifyChanged()
+				continue
+			}
+
+			wait := q.changedSignal()
+			q.lock.Unlock()
+			select {
+			case <-wait:
+			case <-ctx.Done():
+				err = ctx.Err()
+			}
+			q.lock.Lock()
+
+			if err != nil {
+				disp = len(items)
+				if q.onEvict != nil {
+					evicted = CopySlice(items)
+				}
+				break
+			}
+		}
+	default:
+		disp, evicted = q.dropOldestLocked(items)
+	}
+
+	q.notifyChanged()
+	onEvict := q.onEvict
+	q.lock.Unlock()
+
+	if onEvict != nil && len(evicted) > 0 {
+		onEvict(evicted)
+	}
+	return disp, err
+}
+
+// dropOldestLocked pushes the items, dropping the oldest to make room. It
+// returns the number displaced and, if there is an eviction callback, the items
+// dropped. The lock must be held exclusively by the caller.
+func (q *PriorityQueue[Type]) dropOldestLocked(items []Type) (int, []Type) {
+	var evicted []Type
 
-	// If we are over capacity do the quicker copy operation
+	// If we are over capacity replace everything
 	if len(items) >= q.capacity {
-		disp := len(q.items)
-		if len(q.items) < q.capacity {
-			q.items = ResizeSlice(q.items, q.capacity)
+		disp := q.ring.count
+		if q.onEvict != nil {
+			evicted = make([]Type, disp, disp+len(items)-q.capacity)
+			q.ring.read(evicted)
+			evicted = append(evicted, items[:len(items)-q.capacity]...)
 		}
+		q.ring.discard(disp)
+		q.ring.push(items[len(items)-q.capacity:]...)
+		return disp, evicted
+	}
+
+	overlap := Max(len(items)-q.ring.free(), 0)
+	if q.onEvict != nil && overlap > 0 {
+		evicted = make([]Type, overlap)
+		q.ring.read(evicted)
+	}
+	q.ring.discard(overlap)
+	q.ring.push(items...)
+	return overlap, evicted
+}
+
+// priorityQueueSnapshot is the serialized form of a [PriorityQueue].
+type priorityQueueSnapshot[Type any] struct {
+	Capacity int            `json:"capacity"`
+	Policy   OverflowPolicy `json:"policy"`
+	Items    []Type         `json:"items"`
+}
+
+func (q *PriorityQueue[Type]) snapshot() priorityQueueSnapshot[Type] {
+	q.lock.RLock()
+	defer q.lock.RUnlock()
+
+	items := make([]Type, q.ring.count)
+	q.ring.read(items)
+
+	return priorityQueueSnapshot[Type]{
+		Capacity: q.capacity,
+		Policy:   q.policy,
+		Items:    items,
+	}
+}
 
-		items = items[len(items)-q.capacity:]
-		copy(q.items, items)
-		return disp
+// restore replaces the contents and settings of the queue with the snapshot.
+// If there are more items than capacity only the newest are kept.
+func (q *PriorityQueue[Type]) restore(snap priorityQueueSnapshot[Type]) {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	q.capacity = Max(snap.Capacity, 1)
+	q.policy = snap.Policy
+	q.ring = newRingBuffer[Type](q.capacity)
+	q.ring.push(snap.Items[Max(len(snap.Items)-q.capacity, 0):]...)
+	q.notifyChanged()
+}
+
+// MarshalJSON encodes the queue as an object holding its capacity, overflow
+// policy and items, so that it can be restored exactly with [UnmarshalJSON].
+// The eviction callback is not included.
+func (q *PriorityQueue[Type]) MarshalJSON() ([]byte, error) {
+	return JSONMarshaler(q.snapshot())
+}
+
+// UnmarshalJSON decodes a queue encoded by [MarshalJSON], replacing the current
+// contents.
+func (q *PriorityQueue[Type]) UnmarshalJSON(src []byte) error {
+	var snap priorityQueueSnapshot[Type]
+	if err := JSONUnmarshaler(src, &snap); err != nil {
+		return err
 	}
 
-	overlap := 0
+	q.restore(snap)
+	return nil
+}
 
-	space := q.capacity - len(q.items)
-	if len(items) > space {
-		// Putting more items in than we have space for
-		overlap = len(items) - space
-		q.items = q.items[overlap:]
+// MarshalBinary encodes the queue, including its capacity and overflow policy,
+// using [encoding/gob]. The eviction callback is not included.
+func (q *PriorityQueue[Type]) MarshalBinary() ([]byte, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(q.snapshot()); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// UnmarshalBinary decodes a queue encoded by [MarshalBinary], replacing the
+// current contents.
+func (q *PriorityQueue[Type]) UnmarshalBinary(src []byte) error {
+	var snap priorityQueueSnapshot[Type]
+	if err := gob.NewDecoder(bytes.NewReader(src)).Decode(&snap); err != nil {
+		return err
 	}
 
-	// Resize the length up to capacity
-	q.items = ResizeSlice(q.items, Min(len(q.items)+len(items), q.capacity))
+	q.restore(snap)
+	return nil
+}
+
+// GobEncode implements [gob.GobEncoder] using [MarshalBinary].
+func (q *PriorityQueue[Type]) GobEncode() ([]byte, error) {
+	return q.MarshalBinary()
+}
 
-	// Copy in the new items
-	copy(q.items[len(q.items)-len(items):], items)
-	return overlap
+// GobDecode implements [gob.GobDecoder] using [UnmarshalBinary].
+func (q *PriorityQueue[Type]) GobDecode(src []byte) error {
+	return q.UnmarshalBinary(src)
 }
 
 // NewPriorityQueue constructs a new [PriorityQueue] using the given capacity.
-// This capacity is used to allocate the internal slice's capacity at creation
-// time.
+// This capacity is used to allocate the internal buffer at creation time.
 func NewPriorityQueue[Type any](capacity int) PriorityQueue[Type] {
 	capacity = Max(capacity, 1)
 
 	return PriorityQueue[Type]{
 		capacity: capacity,
-		items:    make([]Type, 0, capacity),
+		ring:     newRingBuffer[Type](capacity),
 		lock:     sync.RWMutex{},
 	}
 }
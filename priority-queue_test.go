@@ -1,11 +1,16 @@
 package gox
 
-import "testing"
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
 
 func TestPriorityQueueCapacity(t *testing.T) {
-	q := PriorityQueue[int]{
-		capacity: 5,
-	}
+	q := NewPriorityQueue[int](5)
 
 	if q.Capacity() != 5 {
 		t.Error("incorrect capacity")
@@ -13,10 +18,8 @@ func TestPriorityQueueCapacity(t *testing.T) {
 }
 
 func TestPriorityQueueLength(t *testing.T) {
-	q := PriorityQueue[int]{
-		capacity: 5,
-		items:    []int{0, 1, 2},
-	}
+	q := NewPriorityQueue[int](5)
+	q.Push(0, 1, 2)
 
 	if q.Length() != 3 {
 		t.Error("incorrect length")
@@ -24,10 +27,8 @@ func TestPriorityQueueLength(t *testing.T) {
 }
 
 func TestPriorityQueueSlice(t *testing.T) {
-	q := PriorityQueue[int]{
-		capacity: 5,
-		items:    []int{0, 1, 2},
-	}
+	q := NewPriorityQueue[int](5)
+	q.Push(0, 1, 2)
 
 	slice := q.Slice()
 
@@ -35,21 +36,19 @@ func TestPriorityQueueSlice(t *testing.T) {
 		t.Error("incorrect length")
 	}
 
-	if slice[0] != 0 && slice[len(slice)-1] != 2 {
+	if slice[0] != 0 || slice[len(slice)-1] != 2 {
 		t.Error("incorrect data")
 	}
 
-	q.items[0] = -1
-	if slice[0] == q.items[0] {
-		t.Error("modifying original changed new")
+	slice[0] = -1
+	if q.Peek(1)[0] == slice[0] {
+		t.Error("modifying new changed original")
 	}
 }
 
 func TestPriorityQueuePeek(t *testing.T) {
-	q := PriorityQueue[int]{
-		capacity: 5,
-		items:    []int{0, 1, 2},
-	}
+	q := NewPriorityQueue[int](5)
+	q.Push(0, 1, 2)
 
 	elems := q.Peek(2)
 
@@ -63,17 +62,19 @@ func TestPriorityQueuePeek(t *testing.T) {
 		t.Error("incorrect second element")
 	}
 
-	q.items[1] = -1
-	if elems[1] == q.items[1] {
-		t.Error("modifying original changed new")
+	elems[1] = -1
+	if q.Peek(2)[1] == elems[1] {
+		t.Error("modifying new changed original")
+	}
+
+	if q.Length() != 3 {
+		t.Error("peek modified the queue")
 	}
 }
 
 func TestPriorityQueuePop(t *testing.T) {
-	q := PriorityQueue[int]{
-		capacity: 5,
-		items:    []int{0, 1, 2},
-	}
+	q := NewPriorityQueue[int](5)
+	q.Push(0, 1, 2)
 
 	elems := q.Pop(2)
 
@@ -85,11 +86,18 @@ func TestPriorityQueuePop(t *testing.T) {
 		t.Error("incorrect second element")
 	}
 
-	if len(q.items) != 1 {
+	if q.Length() != 1 {
 		t.Error("original did not resize")
-	} else if q.items[0] != 2 {
+	} else if q.Peek(1)[0] != 2 {
 		t.Error("original did not shift")
 	}
+
+	if elems := q.Pop(5); len(elems) != 1 || elems[0] != 2 {
+		t.Error("pop did not clamp to length", elems)
+	}
+	if elems := q.Pop(1); len(elems) != 0 {
+		t.Error("pop from empty returned something", elems)
+	}
 }
 
 func TestPriorityQueuePush(t *testing.T) {
@@ -100,7 +108,7 @@ func TestPriorityQueuePush(t *testing.T) {
 	if disp != 0 {
 		t.Error("says there was displaced")
 	}
-	if q.items[0] != 1 || q.items[1] != 2 || q.items[2] != 3 {
+	if items := q.Slice(); items[0] != 1 || items[1] != 2 || items[2] != 3 {
 		t.Error("wrong data in queue")
 	}
 
@@ -109,16 +117,315 @@ func TestPriorityQueuePush(t *testing.T) {
 	if disp != 2 {
 		t.Error("incorrect number displaced")
 	}
-	if q.items[0] != 3 || q.items[1] != 4 || q.items[2] != 5 || q.items[3] != 6 || q.items[4] != 7 {
-		t.Error("wrong data in queue", q.items)
+	if items := q.Slice(); items[0] != 3 || items[1] != 4 || items[2] != 5 || items[3] != 6 || items[4] != 7 {
+		t.Error("wrong data in queue", items)
 	}
 
 	// More pushes than capacity
 	q = NewPriorityQueue[int](2)
 	q.Push(1, 2, 3)
-	if len(q.items) != 2 {
-		t.Error("incorrect length", len(q.items))
-	} else if q.items[0] != 2 || q.items[1] != 3 {
-		t.Error("incorrect data", q.items)
+	if items := q.Slice(); len(items) != 2 {
+		t.Error("incorrect length", len(items))
+	} else if items[0] != 2 || items[1] != 3 {
+		t.Error("incorrect data", items)
+	}
+}
+
+func TestPriorityQueueWrap(t *testing.T) {
+	q := NewPriorityQueue[int](4)
+
+	// Push and pop enough to wrap around the buffer several times
+	next, want := 0, 0
+	for i := 0; i < 20; i++ {
+		// Overflow drops the oldest
+		want += q.Push(next, next+1, next+2)
+		next += 3
+
+		for _, v := range q.Pop(2) {
+			if v != want {
+				t.Fatal("popped out of order", v, want)
+			}
+			want++
+		}
+	}
+
+	dst := make([]int, 8)
+	n := q.PopInto(dst)
+	if n == 0 {
+		t.Error("nothing popped into slice")
+	}
+	for _, v := range dst[:n] {
+		if v != want {
+			t.Error("popped into out of order", v, want)
+		}
+		want++
+	}
+	if q.Length() != 0 {
+		t.Error("queue not emptied")
+	}
+}
+
+func BenchmarkPriorityQueuePushPop(b *testing.B) {
+	q := NewPriorityQueue[int](1024)
+	items := []int{1, 2, 3, 4}
+	dst := make([]int, 4)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		q.Push(items...)
+		q.PopInto(dst)
+	}
+}
+
+func BenchmarkPriorityQueueOverflow(b *testing.B) {
+	q := NewPriorityQueue[int](1024)
+	items := []int{1, 2, 3, 4}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		q.Push(items...)
+	}
+}
+
+func TestPriorityQueueConcurrent(t *testing.T) {
+	const producers, consumers, perProducer = 8, 4, 500
+
+	q := NewPriorityQueue[int](producers * perProducer)
+
+	var wg sync.WaitGroup
+	for p := 0; p < producers; p++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < perProducer; i++ {
+				q.Push(1)
+				q.Length()
+				q.Peek(2)
+			}
+		}()
+	}
+
+	var popped atomic.Int64
+	done := make(chan struct{})
+	var consumersWg sync.WaitGroup
+	for c := 0; c < consumers; c++ {
+		consumersWg.Add(1)
+		go func() {
+			defer consumersWg.Done()
+			dst := make([]int, 3)
+			for {
+				select {
+				case <-done:
+					return
+				default:
+				}
+
+				for _, v := range q.Pop(2) {
+					popped.Add(int64(v))
+				}
+				popped.Add(int64(q.PopInto(dst)))
+				q.Slice()
+			}
+		}()
+	}
+
+	wg.Wait()
+	close(done)
+	consumersWg.Wait()
+
+	total := popped.Load() + int64(len(q.Pop(q.Capacity())))
+	if total != producers*perProducer {
+		t.Error("items were lost or duplicated", total)
+	}
+}
+
+func TestPriorityQueuePopWait(t *testing.T) {
+	q := NewPriorityQueue[int](5)
+
+	go func() {
+		time.Sleep(5 * time.Millisecond)
+		q.Push(1, 2, 3)
+	}()
+
+	items, err := q.PopWait(context.Background(), 2)
+	if err != nil {
+		t.Error("unexpected error", err)
+	} else if len(items) == 0 || items[0] != 1 {
+		t.Error("incorrect items", items)
+	}
+
+	q.Pop(q.Capacity())
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Millisecond)
+	defer cancel()
+	if _, err := q.PopWait(ctx, 1); err != context.DeadlineExceeded {
+		t.Error("expected deadline error", err)
+	}
+
+	q.Push(4)
+	if items, err := q.PopWait(context.Background(), 0); err != nil || len(items) != 1 {
+		t.Error("zero count did not pop an item", items, err)
+	}
+}
+
+func TestPriorityQueuePopBatch(t *testing.T) {
+	q := NewPriorityQueue[int](10)
+
+	go func() {
+		for i := 0; i < 4; i++ {
+			time.Sleep(time.Millisecond)
+			q.Push(i)
+		}
+	}()
+
+	items, err := q.PopBatch(context.Background(), 4, time.Second)
+	if err != nil || len(items) != 4 {
+		t.Error("did not wait for a full batch", items, err)
+	}
+
+	q.Push(1)
+	start := time.Now()
+	items, err = q.PopBatch(context.Background(), 4, 10*time.Millisecond)
+	if err != nil || len(items) != 1 {
+		t.Error("did not return a partial batch", items, err)
+	} else if time.Since(start) < 10*time.Millisecond {
+		t.Error("returned before the wait elapsed")
+	}
+}
+
+func TestPriorityQueueOverflowPolicies(t *testing.T) {
+	q := NewPriorityQueue[int](3)
+
+	var evicted []int
+	q.SetEvictionCallback(func(items []int) {
+		evicted = append(evicted, items...)
+	})
+
+	q.Push(1, 2)
+	if disp := q.Push(3, 4); disp != 1 {
+		t.Error("incorrect displaced count", disp)
+	}
+	if len(evicted) != 1 || evicted[0] != 1 {
+		t.Error("evicted items not reported", evicted)
+	}
+
+	evicted = nil
+	q.SetOverflowPolicy(OverflowRejectNewest)
+	if disp := q.Push(5, 6); disp != 2 {
+		t.Error("incorrect rejected count", disp)
+	}
+	if items := q.Slice(); items[0] != 2 || items[2] != 4 {
+		t.Error("rejecting changed the queue", items)
+	}
+	if len(evicted) != 2 || evicted[0] != 5 || evicted[1] != 6 {
+		t.Error("rejected items not reported", evicted)
+	}
+
+	var kept []int
+	q.SetEvictionCallback(func(items []int) {
+		kept = items
+	})
+	pushed := []int{10, 11}
+	q.Push(pushed...)
+	pushed[0] = -1
+	if len(kept) != 2 || kept[0] != 10 {
+		t.Error("evicted items share the pushed slice", kept)
+	}
+	q.SetEvictionCallback(func(items []int) {
+		evicted = append(evicted, items...)
+	})
+
+	q.SetOverflowPolicy(OverflowError)
+	q.Pop(1)
+	evicted = nil
+	if disp, err := q.PushContext(context.Background(), 7, 8); err != ErrQueueFull || disp != 2 {
+		t.Error("expected queue full error", disp, err)
+	}
+	if len(evicted) != 2 || evicted[0] != 7 || evicted[1] != 8 {
+		t.Error("rejected items not reported", evicted)
+	}
+	if _, err := q.PushContext(context.Background(), 7); err != nil || q.Length() != 3 {
+		t.Error("item that fits was not pushed", err)
+	}
+
+	q.SetOverflowPolicy(OverflowBlock)
+	go func() {
+		time.Sleep(5 * time.Millisecond)
+		q.Pop(2)
+	}()
+	if disp, err := q.PushContext(context.Background(), 8, 9); disp != 0 || err != nil {
+		t.Error("blocking push failed", disp, err)
+	}
+	if items := q.Slice(); len(items) != 3 || items[1] != 8 || items[2] != 9 {
+		t.Error("blocking push did not wait for room", items)
+	}
+
+	evicted = nil
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Millisecond)
+	defer cancel()
+	if disp, err := q.PushContext(ctx, 10); disp != 1 || err != context.DeadlineExceeded {
+		t.Error("blocking push did not honour the context", disp, err)
+	}
+	if len(evicted) != 1 || evicted[0] != 10 {
+		t.Error("abandoned items not reported", evicted)
+	}
+}
+
+func TestPriorityQueueSerialization(t *testing.T) {
+	q := NewPriorityQueue[int](4)
+	q.SetOverflowPolicy(OverflowRejectNewest)
+	q.Push(1, 2, 3)
+
+	data, err := json.Marshal(&q)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var fromJSON PriorityQueue[int]
+	if err := json.Unmarshal(data, &fromJSON); err != nil {
+		t.Fatal(err)
+	}
+
+	bin, err := q.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var fromBinary PriorityQueue[int]
+	if err := fromBinary.UnmarshalBinary(bin); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, r := range []*PriorityQueue[int]{&fromJSON, &fromBinary} {
+		if r.Capacity() != 4 || r.policy != OverflowRejectNewest {
+			t.Error("settings were not restored", r.Capacity(), r.policy)
+		}
+		if items := r.Slice(); len(items) != 3 || items[0] != 1 || items[2] != 3 {
+			t.Error("items were not restored", items)
+		}
+		if disp := r.Push(4, 5); disp != 1 {
+			t.Error("restored queue does not behave the same", disp)
+		}
+	}
+}
+
+func TestPriorityQueueRestoreConcurrent(t *testing.T) {
+	q := NewPriorityQueue[int](2)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 100; i++ {
+			q.UnmarshalJSON([]byte(`{"capacity":4,"items":[1,2,3,4]}`))
+		}
+	}()
+
+	for i := 0; i < 100; i++ {
+		if c := q.Capacity(); c != 2 && c != 4 {
+			t.Error("incorrect capacity", c)
+		}
+		q.PopBatch(context.Background(), 3, 0)
 	}
+	<-done
 }
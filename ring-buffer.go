@@ -0,0 +1,131 @@
+package gox
+
+// ringBuffer is a fixed size circular buffer used as the backing store for the
+// queue types. It is not thread-safe, the owning type is expected to lock it.
+//
+// Index 0 is always the oldest element.
+type ringBuffer[Type any] struct {
+	buf   []Type
+	head  int
+	count int
+}
+
+func newRingBuffer[Type any](capacity int) ringBuffer[Type] {
+	return ringBuffer[Type]{
+		buf: make([]Type, capacity),
+	}
+}
+
+// free returns the number of empty slots.
+func (r *ringBuffer[Type]) free() int {
+	return len(r.buf) - r.count
+}
+
+// index converts a position relative to the oldest element into a buffer index.
+func (r *ringBuffer[Type]) index(i int) int {
+	return (r.head + i) % len(r.buf)
+}
+
+// at returns the element at the position, where 0 is the oldest.
+func (r *ringBuffer[Type]) at(i int) Type {
+	return r.buf[r.index(i)]
+}
+
+// push appends the items after the newest element. The caller must make sure
+// there is enough free space.
+func (r *ringBuffer[Type]) push(items ...Type) {
+	if len(items) == 0 {
+		return
+	}
+
+	tail := r.index(r.count)
+	n := copy(r.buf[tail:], items)
+	copy(r.buf, items[n:])
+	r.count += len(items)
+}
+
+// read copies up to len(dst) of the oldest elements into dst without removing
+// them, returning the number copied.
+func (r *ringBuffer[Type]) read(dst []Type) int {
+	count := Min(len(dst), r.count)
+	if count == 0 {
+		return 0
+	}
+
+	end := r.head + count
+	if end <= len(r.buf) {
+		copy(dst, r.buf[r.head:end])
+	} else {
+		n := copy(dst, r.buf[r.head:])
+		copy(dst[n:count], r.buf[:end-len(r.buf)])
+	}
+	return count
+}
+
+// discard removes up to count of the oldest elements, clearing their slots so
+// they can be garbage collected.
+func (r *ringBuffer[Type]) discard(count int) {
+	count = Min(count, r.count)
+	if count <= 0 {
+		return
+	}
+
+	end := r.head + count
+	if end <= len(r.buf) {
+		clear(r.buf[r.head:end])
+	} else {
+		clear(r.buf[r.head:])
+		clear(r.buf[:end-len(r.buf)])
+	}
+
+	if r.count == count {
+		r.head = 0
+	} else {
+		r.head = r.index(count)
+	}
+	r.count -= count
+}
+
+// grow makes room for at least the given number of free slots, reallocating
+// the buffer with the contents moved to the start.
+func (r *ringBuffer[Type]) grow(free int) {
+	if r.free() >= free {
+		return
+	}
+
+	r.resize(Max(len(r.buf)*2, r.count+free, 8))
+}
+
+// resize reallocates the buffer to the given size with the contents moved to
+// the start. The size must fit the current contents.
+func (r *ringBuffer[Type]) resize(size int) {
+	buf := make([]Type, size)
+	r.read(buf)
+	r.buf = buf
+	r.head = 0
+}
+
+// set replaces the element at the position, where 0 is the oldest.
+func (r *ringBuffer[Type]) set(i int, value Type) {
+	r.buf[r.index(i)] = value
+}
+
+// pushFront adds the item before the oldest element. The caller must make sure
+// there is a free slot.
+func (r *ringBuffer[Type]) pushFront(item Type) {
+	r.head = (r.head - 1 + len(r.buf)) % len(r.buf)
+	r.buf[r.head] = item
+	r.count++
+}
+
+// popBack removes and returns the newest element. The caller must make sure
+// the buffer is not empty.
+func (r *ringBuffer[Type]) popBack() Type {
+	ind := r.index(r.count - 1)
+	item := r.buf[ind]
+
+	var zero Type
+	r.buf[ind] = zero
+	r.count--
+	return item
+}
@@ -58,7 +58,7 @@ func SplitStringByRune(str string, run rune) []string {
 			lastInd = i + 1
 		}
 	}
-	if lastInd < len(str)-1 {
+	if lastInd < len(str) {
 		parts = append(parts, str[lastInd:])
 	}
 	return parts
@@ -0,0 +1,20 @@
+package gox
+
+import "testing"
+
+func TestSplitStringByRune(t *testing.T) {
+	parts := SplitStringByRune("users.*", '.')
+	if len(parts) != 2 || parts[0] != "users" || parts[1] != "*" {
+		t.Error("single character trailing segment was dropped", parts)
+	}
+
+	parts = SplitStringByRune("a.bc.d", '.')
+	if len(parts) != 3 || parts[0] != "a" || parts[1] != "bc" || parts[2] != "d" {
+		t.Error("incorrect parts", parts)
+	}
+
+	parts = SplitStringByRune("abc.", '.')
+	if len(parts) != 1 || parts[0] != "abc" {
+		t.Error("trailing rune produced an empty segment", parts)
+	}
+}